@@ -0,0 +1,143 @@
+// Package agecrypt encrypts and decrypts data with age, either to X25519
+// public keys or with a passphrase. age authenticates the whole payload,
+// so tampered or truncated data fails to decrypt.
+package agecrypt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ParseRecipients parses age public keys ("age1...") given directly or as
+// the path of a file with one key per line
+func ParseRecipients(specs []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, spec := range specs {
+		if strings.HasPrefix(spec, "age1") {
+			r, err := age.ParseX25519Recipient(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid public key %s: %w", spec, err)
+			}
+			recipients = append(recipients, r)
+			continue
+		}
+
+		f, err := os.Open(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s is neither an age public key nor a readable file: %w", spec, err)
+		}
+		rs, err := age.ParseRecipients(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public keys in %s: %w", spec, err)
+		}
+		recipients = append(recipients, rs...)
+	}
+	return recipients, nil
+}
+
+// LoadIdentities reads age private keys ("AGE-SECRET-KEY-1...") from a
+// file
+func LoadIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open identity file: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// Passphrase returns a recipient and identity for a passphrase
+func Passphrase(passphrase string) (age.Recipient, age.Identity, error) {
+	r, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	i, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, i, nil
+}
+
+// Encrypt encrypts data to the recipients, ASCII-armored if armored is set
+func Encrypt(data []byte, armored bool, recipients ...age.Recipient) ([]byte, error) {
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(&buf)
+		out = armorWriter
+	}
+
+	w, err := age.Encrypt(out, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt decrypts binary or ASCII-armored data, verifying it completely
+// before returning it
+func Decrypt(data []byte, identities ...age.Identity) ([]byte, error) {
+	var in io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		in = armor.NewReader(in)
+	}
+
+	r, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (data corrupted or truncated): %w", err)
+	}
+	return plain, nil
+}
+
+// IsPassphrase reports whether data was encrypted with a passphrase rather
+// than to public keys
+func IsPassphrase(data []byte) bool {
+	var in io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		in = armor.NewReader(in)
+	}
+
+	// The header lists one "-> TYPE ..." stanza per recipient
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "-> scrypt ") {
+			return true
+		}
+		if strings.HasPrefix(line, "---") {
+			break
+		}
+	}
+	return false
+}
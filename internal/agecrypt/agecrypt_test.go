@@ -0,0 +1,77 @@
+package agecrypt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestPublicKeyRoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := ParseRecipients([]string{identity.Recipient().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, armored := range []bool{false, true} {
+		sealed, err := Encrypt([]byte("secret"), armored, recipients...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IsPassphrase(sealed) {
+			t.Error("public-key data reported as passphrase-encrypted")
+		}
+
+		identities, err := LoadIdentities(keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := Decrypt(sealed, identities...)
+		if err != nil {
+			t.Fatalf("armored=%v: %v", armored, err)
+		}
+		if string(plain) != "secret" {
+			t.Errorf("got %q", plain)
+		}
+	}
+}
+
+func TestPassphraseDetectsTampering(t *testing.T) {
+	r, i, err := Passphrase("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Encrypt(bytes.Repeat([]byte("x"), 1000), false, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPassphrase(sealed) {
+		t.Error("passphrase data not detected")
+	}
+
+	if _, err := Decrypt(sealed, i); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed[len(sealed)-10] ^= 1
+	if _, err := Decrypt(sealed, i); err == nil {
+		t.Error("expected tampered data to fail")
+	}
+
+	_, wrong, _ := Passphrase("wrong")
+	if _, err := Decrypt(sealed[:len(sealed)-1], wrong); err == nil {
+		t.Error("expected wrong passphrase to fail")
+	}
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/plan"
+)
+
+// variableMap returns the variables as a key-value map
+func variableMap(variables []variable) map[string]string {
+	m := make(map[string]string, len(variables))
+	for _, v := range variables {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(reader *bufio.Reader, question string) (bool, error) {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// resolveConflicts asks for every changed key whether to overwrite it, and
+// keeps the current value of those declined
+func resolveConflicts(reader *bufio.Reader, p *plan.Plan) error {
+	for i, it := range p.Items {
+		if it.Action != plan.Change {
+			continue
+		}
+		ok, err := confirm(reader, fmt.Sprintf("Overwrite %s (%s -> %s)?", it.Key, plan.Fingerprint(it.Old), plan.Fingerprint(it.New)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			p.Items[i].Action = plan.Keep
+		}
+	}
+	return nil
+}
+
+// setVariable writes a single variable
+func setVariable(cl *client.Client, key, value string) error {
+	resp, err := cl.Put(fmt.Sprintf("/variables/%s", key), map[string]interface{}{
+		"value": value,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 204 {
+		return client.ParseResponse(resp, nil)
+	}
+	resp.Body.Close()
+	clearHookCache()
+	return nil
+}
+
+// deleteVariable deletes a single variable; a missing variable is not an
+// error
+func deleteVariable(cl *client.Client, key string) error {
+	resp, err := cl.Delete(fmt.Sprintf("/variables/%s", key))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 404 {
+		// Already absent
+		resp.Body.Close()
+		return nil
+	}
+	if resp.StatusCode != 204 {
+		return client.ParseResponse(resp, nil)
+	}
+	resp.Body.Close()
+	clearHookCache()
+	return nil
+}
+
+// applyPlan performs the additions, changes and removals of a plan, and
+// nothing else. Failed keys are reported as warnings; the returned error
+// counts them.
+func applyPlan(cl *client.Client, p *plan.Plan) error {
+	failed := applyItems(cl, p)
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d changes failed", len(failed), p.Count(plan.Add)+p.Count(plan.Change)+p.Count(plan.Remove))
+	}
+	return nil
+}
+
+// applyItems performs the changes of a plan, warning about and returning
+// the errors of failed keys
+func applyItems(cl *client.Client, p *plan.Plan) map[string]error {
+	failed := make(map[string]error)
+	for _, it := range p.Items {
+		if err := applyItem(cl, it); err != nil {
+			failed[it.Key] = err
+		}
+	}
+	return failed
+}
+
+// applyItem performs the change of one plan item, warning if it fails.
+// Items that change nothing are skipped.
+func applyItem(cl *client.Client, it plan.Item) error {
+	var err error
+	switch it.Action {
+	case plan.Add, plan.Change:
+		err = setVariable(cl, it.Key, it.New)
+	case plan.Remove:
+		err = deleteVariable(cl, it.Key)
+	default:
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to %s %s: %v\n", it.Action, it.Key, err)
+	}
+	return err
+}
@@ -0,0 +1,476 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"filippo.io/age"
+
+	"not-env-cli/internal/agecrypt"
+	"not-env-cli/internal/atomicfile"
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/plan"
+)
+
+// backupVersion is the version of the archive format
+const backupVersion = 1
+
+// backupArchive is the plaintext of an encrypted backup. Checksum is the
+// SHA-256 of the JSON encoding of Environments.
+type backupArchive struct {
+	Version      int                 `json:"version"`
+	CreatedAt    string              `json:"created_at"`
+	Environments []backupEnvironment `json:"environments"`
+	Checksum     string              `json:"checksum"`
+}
+
+type backupEnvironment struct {
+	Profile     string            `json:"profile,omitempty"`
+	URL         string            `json:"url"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Variables   map[string]string `json:"variables"`
+}
+
+// BackupOptions controls what is backed up and how it is encrypted
+type BackupOptions struct {
+	// Current only backs up the current environment instead of every
+	// environment with a saved key
+	Current bool
+	// Recipients are age public keys (or files of them) to encrypt to;
+	// without them a passphrase is used
+	Recipients []string
+}
+
+// RestoreOptions controls how a backup is restored
+type RestoreOptions struct {
+	// Env selects one environment of the archive by name
+	Env string
+	// Into restores the selected environment under another name
+	Into string
+	// Identity is the age private key file for public-key backups
+	Identity string
+	// Prune removes variables not in the backup (ENV_ADMIN restores)
+	Prune bool
+	// Yes skips the confirmation of ENV_ADMIN restores
+	Yes bool
+}
+
+// checksum returns the checksum of the archive's environments
+func (a *backupArchive) checksum() (string, error) {
+	data, err := json.Marshal(a.Environments)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verify checks the archive's version, checksum and contents
+func (a *backupArchive) verify() error {
+	if a.Version != backupVersion {
+		return fmt.Errorf("unsupported backup version %d", a.Version)
+	}
+	sum, err := a.checksum()
+	if err != nil {
+		return err
+	}
+	if sum != a.Checksum {
+		return fmt.Errorf("backup checksum mismatch: the archive is corrupted")
+	}
+	for _, env := range a.Environments {
+		if err := validateEnvironmentName(env.Name); err != nil {
+			return fmt.Errorf("invalid environment in backup: %w", err)
+		}
+		if _, ok := env.Variables[""]; ok {
+			return fmt.Errorf("invalid backup: environment %s has an empty key", env.Name)
+		}
+	}
+	return nil
+}
+
+// Backup writes an encrypted archive of the metadata and variables of every
+// environment with a saved key (or only the current one) to out. Keys that
+// no longer work are skipped with a warning and reported as an error after
+// the archive is written.
+func Backup(out string, opts BackupOptions) error {
+	credentials, err := backupCredentials(opts.Current)
+	if err != nil {
+		return err
+	}
+
+	var recipients []age.Recipient
+	if len(opts.Recipients) > 0 {
+		recipients, err = agecrypt.ParseRecipients(opts.Recipients)
+		if err != nil {
+			return err
+		}
+	}
+
+	archive := backupArchive{
+		Version:   backupVersion,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	total, skipped := 0, 0
+	for _, c := range credentials {
+		cl := client.NewClient(c.cfg.URL, c.cfg.APIKey)
+		env, err := fetchEnvironment(cl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", c.label(), err)
+			skipped++
+			continue
+		}
+		variables, err := fetchVariables(cl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", c.label(), err)
+			skipped++
+			continue
+		}
+		archive.Environments = append(archive.Environments, backupEnvironment{
+			Profile:     c.profile,
+			URL:         c.cfg.URL,
+			Name:        env.Name,
+			Description: env.Description,
+			Variables:   variableMap(variables),
+		})
+		total += len(variables)
+	}
+	if len(archive.Environments) == 0 {
+		return fmt.Errorf("no saved ENV_ADMIN or ENV_READ_ONLY keys to back up")
+	}
+
+	archive.Checksum, err = archive.checksum()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+
+	if recipients == nil {
+		passphrase, err := readPassphrase(true)
+		if err != nil {
+			return err
+		}
+		r, _, err := agecrypt.Passphrase(passphrase)
+		if err != nil {
+			return err
+		}
+		recipients = []age.Recipient{r}
+	}
+
+	sealed, err := agecrypt.Encrypt(data, false, recipients...)
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.Write(out, sealed); err != nil {
+		return err
+	}
+
+	fmt.Printf("Backed up %d environments (%d variables) to %s\n", len(archive.Environments), total, out)
+	if skipped > 0 {
+		return fmt.Errorf("%d saved keys could not be backed up", skipped)
+	}
+	return nil
+}
+
+// savedCredentials is a set of saved credentials and the profile it comes
+// from ("" for the top-level credentials)
+type savedCredentials struct {
+	profile string
+	cfg     *config.Config
+}
+
+func (c savedCredentials) label() string {
+	if c.profile == "" {
+		return "default credentials"
+	}
+	return "profile " + c.profile
+}
+
+// backupCredentials returns the credentials to back up, one per
+// environment. APP_ADMIN keys cannot read variables and are skipped.
+func backupCredentials(current bool) ([]savedCredentials, error) {
+	if current {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		if cfg.KeyType == "APP_ADMIN" {
+			return nil, fmt.Errorf("APP_ADMIN keys cannot read variables; use an environment's key")
+		}
+		return []savedCredentials{{profile: config.ActiveProfile(), cfg: cfg}}, nil
+	}
+
+	file, err := config.LoadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	all := []savedCredentials{{cfg: file}}
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cfg, err := file.WithProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, savedCredentials{profile: name, cfg: cfg})
+	}
+
+	var result []savedCredentials
+	seen := make(map[string]bool)
+	for _, c := range all {
+		if c.cfg.APIKey == "" || c.cfg.KeyType == "APP_ADMIN" {
+			continue
+		}
+		// The same environment may be saved more than once
+		if c.cfg.EnvIDFromKey != nil {
+			id := fmt.Sprintf("%s|%d", c.cfg.URL, *c.cfg.EnvIDFromKey)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// readBackup decrypts and verifies a backup file
+func readBackup(path, identity string) (*backupArchive, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var identities []age.Identity
+	usesPassphrase := agecrypt.IsPassphrase(sealed)
+	if usesPassphrase {
+		passphrase, err := readPassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		_, i, err := agecrypt.Passphrase(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = []age.Identity{i}
+	} else {
+		if identity == "" {
+			return nil, fmt.Errorf("%s is encrypted to a public key; pass the private key file with --identity", path)
+		}
+		identities, err = agecrypt.LoadIdentities(identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := agecrypt.Decrypt(sealed, identities...)
+	var noMatch *age.NoIdentityMatchError
+	if usesPassphrase && errors.As(err, &noMatch) {
+		return nil, fmt.Errorf("failed to decrypt %s: incorrect passphrase", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archive backupArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	if err := archive.verify(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// Restore recreates the environments of a backup. The archive is decrypted
+// and verified completely before anything is written. With an APP_ADMIN
+// key, environments are created (and their new keys saved as profiles);
+// with an ENV_ADMIN key, one environment's variables are restored into the
+// current environment.
+func Restore(path string, opts RestoreOptions) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.KeyType != "APP_ADMIN" && cfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("invalid key type: %s (only APP_ADMIN and ENV_ADMIN can restore)", cfg.KeyType)
+	}
+
+	archive, err := readBackup(path, opts.Identity)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Verified backup of %s with %d environments\n", archive.CreatedAt, len(archive.Environments))
+
+	envs := archive.Environments
+	if opts.Env != "" {
+		envs = nil
+		for _, env := range archive.Environments {
+			if env.Name == opts.Env {
+				envs = append(envs, env)
+			}
+		}
+		if len(envs) == 0 {
+			return fmt.Errorf("environment '%s' not found in backup", opts.Env)
+		}
+	}
+	if (opts.Into != "" || cfg.KeyType == "ENV_ADMIN") && len(envs) != 1 {
+		return fmt.Errorf("the backup has %d environments; select one with --env", len(envs))
+	}
+
+	if cfg.KeyType == "ENV_ADMIN" {
+		if opts.Into != "" {
+			return fmt.Errorf("--into needs an APP_ADMIN key; an ENV_ADMIN key restores into its own environment")
+		}
+		return restoreInto(cfg, envs[0], opts)
+	}
+
+	if opts.Into != "" {
+		if err := validateEnvironmentName(opts.Into); err != nil {
+			return err
+		}
+		envs[0].Name = opts.Into
+	}
+
+	// Check every name before creating anything
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+	existing, err := environmentNames(cl)
+	if err != nil {
+		return fmt.Errorf("failed to list environments: %w", err)
+	}
+	for _, env := range envs {
+		if existing[env.Name] {
+			return fmt.Errorf("environment '%s' already exists (restore it under another name with --into, or into it with its ENV_ADMIN key)", env.Name)
+		}
+	}
+
+	file, err := config.LoadFile()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, env := range envs {
+		created, err := createEnvironment(cl, env.Name, env.Description)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to create %s: %v\n", env.Name, err)
+			failed++
+			continue
+		}
+
+		p := plan.New(nil, env.Variables, plan.Options{})
+		if err := applyPlan(client.NewClient(cfg.URL, created.Keys.EnvAdmin), p); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", env.Name, err)
+			failed++
+		}
+
+		fmt.Printf("\nEnvironment '%s' restored with %d variables\n", env.Name, len(env.Variables))
+		fmt.Printf("ENV_ADMIN key: %s\n", created.Keys.EnvAdmin)
+		fmt.Printf("ENV_READ_ONLY key: %s\n", created.Keys.EnvReadOnly)
+
+		// Save the new key under the original profile name, unless that
+		// profile still holds other credentials
+		profile := env.Profile
+		if profile == "" || opts.Into != "" {
+			profile = env.Name
+		}
+		if _, exists := file.Profiles[profile]; exists {
+			fmt.Printf("Profile '%s' already exists; the new keys were not saved.\n", profile)
+			continue
+		}
+		id := created.ID
+		file.SetProfileCredentials(profile, config.Profile{
+			URL:          cfg.URL,
+			APIKey:       created.Keys.EnvAdmin,
+			KeyType:      "ENV_ADMIN",
+			EnvIDFromKey: &id,
+		})
+		// Save each profile right away, so the keys of environments that
+		// were created survive a later failure
+		if err := file.Save(); err != nil {
+			return fmt.Errorf("failed to save profile '%s': %w", profile, err)
+		}
+		fmt.Printf("Saved the ENV_ADMIN key as profile '%s'.\n", profile)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d environments were not restored completely", failed)
+	}
+	return nil
+}
+
+// restoreInto restores an environment's variables into the environment of
+// an ENV_ADMIN key, showing the plan first
+func restoreInto(cfg *config.Config, env backupEnvironment, opts RestoreOptions) error {
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+	current, err := fetchVariables(cl)
+	if err != nil {
+		return fmt.Errorf("failed to fetch current variables: %w", err)
+	}
+
+	p := plan.New(variableMap(current), env.Variables, plan.Options{Prune: opts.Prune})
+	fmt.Printf("Restoring '%s' into the current environment\n", env.Name)
+	p.Print(os.Stdout, false)
+	if p.Empty() {
+		fmt.Println("Nothing to change.")
+		return nil
+	}
+
+	if !opts.Yes {
+		ok, err := confirm(bufio.NewReader(os.Stdin), "Apply this plan?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Restore cancelled.")
+			return nil
+		}
+	}
+
+	if err := applyPlan(cl, p); err != nil {
+		return err
+	}
+	fmt.Printf("Restored: %d added, %d changed, %d removed\n", p.Count(plan.Add), p.Count(plan.Change), p.Count(plan.Remove))
+	return nil
+}
+
+// environmentNames returns the names of all environments (APP_ADMIN)
+func environmentNames(cl *client.Client) (map[string]bool, error) {
+	resp, err := cl.Get("/environments")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, client.ParseResponse(resp, nil)
+	}
+
+	var envs struct {
+		Environments []struct {
+			Name string `json:"name"`
+		} `json:"environments"`
+	}
+	if err := client.ParseResponse(resp, &envs); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(envs.Environments))
+	for _, env := range envs.Environments {
+		names[env.Name] = true
+	}
+	return names, nil
+}
@@ -0,0 +1,32 @@
+package commands
+
+import "testing"
+
+func TestBackupArchiveVerify(t *testing.T) {
+	archive := backupArchive{
+		Version: backupVersion,
+		Environments: []backupEnvironment{
+			{Name: "dev", Variables: map[string]string{"A": "1"}},
+		},
+	}
+	sum, err := archive.checksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive.Checksum = sum
+
+	if err := archive.verify(); err != nil {
+		t.Fatalf("valid archive rejected: %v", err)
+	}
+
+	archive.Environments[0].Variables["A"] = "2"
+	if err := archive.verify(); err == nil {
+		t.Error("expected checksum mismatch after modification")
+	}
+
+	archive.Environments[0].Variables["A"] = "1"
+	archive.Version = 99
+	if err := archive.verify(); err == nil {
+		t.Error("expected unsupported version error")
+	}
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CheckOptions controls what 'env check' compares against
+type CheckOptions struct {
+	// Against is a file (e.g. .env.example, any import format) whose keys
+	// are required; empty uses [check] required of the project file
+	Against string
+	// AllowExtra does not fail on keys that are not required
+	AllowExtra bool
+	// JSON prints the result as JSON
+	JSON bool
+}
+
+// checkResult is the outcome of 'env check'
+type checkResult struct {
+	OK       bool     `json:"ok"`
+	Required int      `json:"required"`
+	Missing  []string `json:"missing"`
+	Empty    []string `json:"empty"`
+	Extra    []string `json:"extra"`
+}
+
+// EnvCheck compares the current environment with a list of required keys
+// and reports missing keys, empty values and unexpected extra keys. A
+// failed check is reported with an *ExitError of code 1.
+func EnvCheck(opts CheckOptions) error {
+	required, err := requiredKeys(opts.Against)
+	if err != nil {
+		return err
+	}
+
+	cl, err := profileClient("")
+	if err != nil {
+		return err
+	}
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return err
+	}
+
+	result := checkVariables(required, variableMap(variables))
+	result.OK = len(result.Missing) == 0 && len(result.Empty) == 0 &&
+		(opts.AllowExtra || len(result.Extra) == 0)
+
+	if opts.JSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printCheck(result)
+	}
+
+	if !result.OK {
+		return &ExitError{Code: 1}
+	}
+	return nil
+}
+
+// requiredKeys returns the keys of the file against, or those listed in
+// the project file
+func requiredKeys(against string) ([]string, error) {
+	if against != "" {
+		variables, err := readImportSource(ImportSource{File: against})
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, len(variables))
+		for i, v := range variables {
+			keys[i] = v.Key
+		}
+		return keys, nil
+	}
+
+	project, err := currentProject()
+	if err != nil {
+		return nil, err
+	}
+	if project == nil || len(project.Check.Required) == 0 {
+		return nil, fmt.Errorf("no required keys: pass --against FILE or list them under [check] required in .not-env.toml")
+	}
+	return project.Check.Required, nil
+}
+
+// checkVariables sorts the keys into missing, empty and extra
+func checkVariables(required []string, variables map[string]string) checkResult {
+	result := checkResult{
+		Required: len(required),
+		Missing:  []string{},
+		Empty:    []string{},
+		Extra:    []string{},
+	}
+
+	want := make(map[string]bool, len(required))
+	for _, key := range required {
+		want[key] = true
+		value, ok := variables[key]
+		switch {
+		case !ok:
+			result.Missing = append(result.Missing, key)
+		case value == "":
+			result.Empty = append(result.Empty, key)
+		}
+	}
+	for key := range variables {
+		if !want[key] {
+			result.Extra = append(result.Extra, key)
+		}
+	}
+
+	sort.Strings(result.Missing)
+	sort.Strings(result.Empty)
+	sort.Strings(result.Extra)
+	return result
+}
+
+// printCheck prints a check result for humans
+func printCheck(result checkResult) {
+	for _, group := range []struct {
+		label string
+		keys  []string
+	}{
+		{"Missing", result.Missing},
+		{"Empty", result.Empty},
+		{"Unexpected", result.Extra},
+	} {
+		if len(group.keys) > 0 {
+			fmt.Printf("%s (%d): %s\n", group.label, len(group.keys), strings.Join(group.keys, ", "))
+		}
+	}
+
+	if result.OK {
+		fmt.Printf("OK: all %d required keys are set\n", result.Required)
+	} else {
+		fmt.Fprintln(os.Stderr, "Check failed")
+	}
+}
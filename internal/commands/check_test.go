@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckVariables(t *testing.T) {
+	result := checkVariables(
+		[]string{"SET", "MISSING", "EMPTY"},
+		map[string]string{"SET": "x", "EMPTY": "", "EXTRA": "y"},
+	)
+
+	if !reflect.DeepEqual(result.Missing, []string{"MISSING"}) {
+		t.Errorf("missing = %v", result.Missing)
+	}
+	if !reflect.DeepEqual(result.Empty, []string{"EMPTY"}) {
+		t.Errorf("empty = %v", result.Empty)
+	}
+	if !reflect.DeepEqual(result.Extra, []string{"EXTRA"}) {
+		t.Errorf("extra = %v", result.Extra)
+	}
+	if result.Required != 3 {
+		t.Errorf("required = %d, want 3", result.Required)
+	}
+}
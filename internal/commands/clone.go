@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/dotenv"
+	"not-env-cli/internal/filter"
+	"not-env-cli/internal/plan"
+)
+
+// CloneOptions controls how an environment is cloned
+type CloneOptions struct {
+	Description string
+	// Only selects the source keys to copy (glob patterns)
+	Only []string
+	// Set adds or overrides variables, as KEY=VALUE
+	Set []string
+	// Profile is the profile the new keys are saved under (default: the
+	// new environment's name); NoSave skips saving them
+	Profile string
+	NoSave  bool
+}
+
+// EnvClone creates environment name with the APP_ADMIN key and copies the
+// variables of from (a profile, stored environment or file, see
+// ParseEnvRef) into it with the new ENV_ADMIN key. The new keys are
+// printed and saved as a profile.
+func EnvClone(from, name string, opts CloneOptions) error {
+	if err := validateEnvironmentName(name); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.KeyType != "APP_ADMIN" {
+		return fmt.Errorf("invalid key type: %s (cloning creates an environment and requires APP_ADMIN)", cfg.KeyType)
+	}
+
+	overrides, err := parseAssignments(opts.Set)
+	if err != nil {
+		return err
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = name
+	}
+	file, err := config.LoadFile()
+	if err != nil {
+		return err
+	}
+	if _, exists := file.Profiles[profile]; exists && !opts.NoSave {
+		return fmt.Errorf("profile '%s' already exists (choose another with --save-profile or use --no-save)", profile)
+	}
+
+	ref, err := ParseEnvRef(from)
+	if err != nil {
+		return err
+	}
+	variables, err := readEnvRef(ref)
+	if err != nil {
+		return err
+	}
+	variables, err = filterVariables(variables, filter.Options{Only: opts.Only})
+	if err != nil {
+		return err
+	}
+
+	desired := variableMap(variables)
+	for _, v := range overrides {
+		desired[v.Key] = v.Value
+	}
+	p := plan.New(nil, desired, plan.Options{})
+
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+	created, err := createEnvironment(cl, name, opts.Description)
+	if err != nil {
+		return err
+	}
+
+	// Write with the new environment's own key
+	applyErr := applyPlan(client.NewClient(cfg.URL, created.Keys.EnvAdmin), p)
+
+	fmt.Printf("Environment '%s' cloned from %s with %d variables!\n", name, ref, p.Count(plan.Add))
+	fmt.Printf("ENV_ADMIN key: %s\n", created.Keys.EnvAdmin)
+	fmt.Printf("ENV_READ_ONLY key: %s\n", created.Keys.EnvReadOnly)
+
+	if !opts.NoSave {
+		id := created.ID
+		file.SetProfileCredentials(profile, config.Profile{
+			URL:          cfg.URL,
+			APIKey:       created.Keys.EnvAdmin,
+			KeyType:      "ENV_ADMIN",
+			EnvIDFromKey: &id,
+		})
+		if err := file.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save profile '%s': %v\n", profile, err)
+		} else {
+			fmt.Printf("\nSaved the ENV_ADMIN key as profile '%s' (use --profile %s).\n", profile, profile)
+		}
+	}
+	fmt.Println("Save the ENV_READ_ONLY key for applications (SDKs).")
+
+	return applyErr
+}
+
+// parseAssignments parses KEY=VALUE arguments
+func parseAssignments(args []string) ([]variable, error) {
+	variables := make([]variable, 0, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid assignment %q (expected KEY=VALUE)", arg)
+		}
+		if !dotenv.ValidKey(key) {
+			return nil, fmt.Errorf("invalid assignment %q: %q is not a valid key", arg, key)
+		}
+		variables = append(variables, variable{Key: key, Value: value})
+	}
+	return variables, nil
+}
@@ -0,0 +1,22 @@
+package commands
+
+import "testing"
+
+func TestParseAssignments(t *testing.T) {
+	got, err := parseAssignments([]string{"A=1", "B=x=y", "C="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []variable{{Key: "A", Value: "1"}, {Key: "B", Value: "x=y"}, {Key: "C", Value: ""}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"NOEQUALS", "=value", "BAD KEY=1", "A-B=1", "../x=1"} {
+		if _, err := parseAssignments([]string{bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"not-env-cli/internal/plan"
+	"not-env-cli/internal/textdiff"
+)
+
+// EnvDiff compares two environments, profiles or files (see ParseEnvRef)
+// and prints the keys only in a, only in b and those whose values differ.
+// Values are masked as fingerprints (see plan.Fingerprint) unless showValues
+// is set; differing multi-line values are then shown as a unified diff.
+// Differences are reported with an *ExitError of code 1.
+func EnvDiff(specA, specB string, showValues bool) error {
+	refA, err := ParseEnvRef(specA)
+	if err != nil {
+		return err
+	}
+	refB, err := ParseEnvRef(specB)
+	if err != nil {
+		return err
+	}
+
+	varsA, err := readEnvRef(refA)
+	if err != nil {
+		return err
+	}
+	varsB, err := readEnvRef(refB)
+	if err != nil {
+		return err
+	}
+
+	a, b := variableMap(varsA), variableMap(varsB)
+	onlyA, onlyB, differing := compareMaps(a, b)
+
+	if len(onlyA)+len(onlyB)+len(differing) == 0 {
+		fmt.Println("No differences.")
+		return nil
+	}
+
+	show := plan.Fingerprint
+	if showValues {
+		show = func(value string) string {
+			return fmt.Sprintf("%q", value)
+		}
+	}
+
+	width := 0
+	for _, keys := range [][]string{onlyA, onlyB, differing} {
+		for _, key := range keys {
+			if len(key) > width {
+				width = len(key)
+			}
+		}
+	}
+
+	if len(onlyA) > 0 {
+		fmt.Printf("Only in %s:\n", refA)
+		for _, key := range onlyA {
+			fmt.Printf("  %-*s  %s\n", width, key, show(a[key]))
+		}
+	}
+	if len(onlyB) > 0 {
+		fmt.Printf("Only in %s:\n", refB)
+		for _, key := range onlyB {
+			fmt.Printf("  %-*s  %s\n", width, key, show(b[key]))
+		}
+	}
+	if len(differing) > 0 {
+		fmt.Println("Different values:")
+		for _, key := range differing {
+			if showValues && (strings.Contains(a[key], "\n") || strings.Contains(b[key], "\n")) {
+				fmt.Printf("  %s:\n", key)
+				diff := textdiff.Unified(refA.String()+" "+key, refB.String()+" "+key, a[key], b[key])
+				for _, line := range strings.SplitAfter(strings.TrimSuffix(diff, "\n"), "\n") {
+					fmt.Printf("    %s", line)
+				}
+				fmt.Println()
+				continue
+			}
+			fmt.Printf("  %-*s  %s -> %s\n", width, key, show(a[key]), show(b[key]))
+		}
+	}
+
+	fmt.Printf("%d only in %s, %d only in %s, %d different\n", len(onlyA), refA, len(onlyB), refB, len(differing))
+	return &ExitError{Code: 1}
+}
+
+// compareMaps returns the sorted keys only in a, only in b and in both
+// with different values
+func compareMaps(a, b map[string]string) (onlyA, onlyB, differing []string) {
+	for key, value := range a {
+		other, ok := b[key]
+		switch {
+		case !ok:
+			onlyA = append(onlyA, key)
+		case other != value:
+			differing = append(differing, key)
+		}
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			onlyB = append(onlyB, key)
+		}
+	}
+
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	sort.Strings(differing)
+	return onlyA, onlyB, differing
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"not-env-cli/internal/config"
+)
+
+func TestCompareMaps(t *testing.T) {
+	a := map[string]string{"SAME": "1", "DIFF": "a", "ONLY_A": "x"}
+	b := map[string]string{"SAME": "1", "DIFF": "b", "ONLY_B": "y", "ALSO_B": "z"}
+
+	onlyA, onlyB, differing := compareMaps(a, b)
+	if !reflect.DeepEqual(onlyA, []string{"ONLY_A"}) {
+		t.Errorf("onlyA = %v", onlyA)
+	}
+	if !reflect.DeepEqual(onlyB, []string{"ALSO_B", "ONLY_B"}) {
+		t.Errorf("onlyB = %v", onlyB)
+	}
+	if !reflect.DeepEqual(differing, []string{"DIFF"}) {
+		t.Errorf("differing = %v", differing)
+	}
+}
+
+func TestParseEnvRef(t *testing.T) {
+	// Keep the lookup of stored environments away from the real config
+	previous := config.SetConfigPath(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() { config.SetConfigPath(previous) })
+
+	tests := map[string]EnvRef{
+		"profile:dev":  {Profile: "dev"},
+		"env:staging":  {Env: "staging"},
+		"file:x.env":   {File: "x.env"},
+		"./local.json": {File: "./local.json"},
+		"staging":      {Env: "staging"},
+	}
+	for spec, want := range tests {
+		got, err := ParseEnvRef(spec)
+		if err != nil {
+			t.Errorf("%s: %v", spec, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %+v, want %+v", spec, got, want)
+		}
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strings"
 
 	"not-env-cli/internal/client"
 	"not-env-cli/internal/config"
+	"not-env-cli/internal/plan"
+	"not-env-cli/internal/shell"
 )
 
 // validateEnvironmentName validates an environment name
@@ -183,20 +184,43 @@ func EnvDelete(envID int64) error {
 	if resp.StatusCode != 204 {
 		return client.ParseResponse(resp, nil)
 	}
+	clearHookCache()
 
 	fmt.Printf("Environment %d deleted successfully!\n", envID)
 	return nil
 }
 
-// EnvImport imports variables from a .env file.
+// ImportOptions controls how an import is planned and applied
+type ImportOptions struct {
+	// Overwrite allows an APP_ADMIN import into an existing environment
+	Overwrite bool
+	// Plan only prints the plan without applying it
+	Plan bool
+	// Prune removes variables that are not in the source
+	Prune bool
+	// NoOverwrite only adds missing variables
+	NoOverwrite bool
+	// Interactive asks before overwriting each changed variable and before
+	// applying the plan
+	Interactive bool
+	// Atomic snapshots the variables to change and restores them if the
+	// import fails or is interrupted (ENV_ADMIN only)
+	Atomic bool
+	// NoValidate skips checking the values against the schema file
+	NoValidate bool
+}
+
+// EnvImport imports variables from a .env file or another supported
+// format (see ImportSource).
 // Flow:
-//  1. Parses .env file into key-value pairs
-//  2. Creates environment via API (or uses existing if overwrite=true) - APP_ADMIN only
-//  3. Gets both ENV_ADMIN and ENV_READ_ONLY keys from API response - APP_ADMIN only
-//  4. Sets all variables from .env file
-//  5. Outputs both keys for user (ENV_ADMIN for CLI, ENV_READ_ONLY for SDKs) - APP_ADMIN only
+//  1. Parses the input file into key-value pairs
+//  2. Compares them with the current variables and prints the plan (values masked)
+//  3. Creates environment via API (or uses existing if overwrite=true) - APP_ADMIN only
+//  4. Gets both ENV_ADMIN and ENV_READ_ONLY keys from API response - APP_ADMIN only
+//  5. Applies exactly the printed plan
+//  6. Outputs both keys for user (ENV_ADMIN for CLI, ENV_READ_ONLY for SDKs) - APP_ADMIN only
 // For ENV_ADMIN: imports directly into their environment (no creation needed)
-func EnvImport(name, description, filePath string, overwrite bool) error {
+func EnvImport(name, description string, src ImportSource, opts ImportOptions) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
@@ -215,49 +239,22 @@ func EnvImport(name, description, filePath string, overwrite bool) error {
 		}
 	}
 
-	// Read .env file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	// Parse .env file
-	// Supports: KEY=VALUE, KEY="value", KEY='value', comments (#), empty lines
-	envVars := make(map[string]string)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Split on first = to handle values that contain =
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		// Remove surrounding quotes (both single and double) if present
-		if len(value) >= 2 {
-			if (value[0] == '"' && value[len(value)-1] == '"') ||
-				(value[0] == '\'' && value[len(value)-1] == '\'') {
-				value = value[1 : len(value)-1]
-			}
-		}
+	if opts.Atomic && cfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("--atomic applies to imports into an existing environment (ENV_ADMIN key)")
+	}
 
-		envVars[key] = value
+	if opts.Interactive && (src.File == "-" || src.Share == "-") {
+		return fmt.Errorf("--interactive cannot be used when reading the file from stdin")
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+	// Read input file
+	envVars, err := readImportSource(src)
+	if err != nil {
+		return err
 	}
 
 	cl := client.NewClient(cfg.URL, cfg.APIKey)
+	planOpts := plan.Options{Prune: opts.Prune, NoOverwrite: opts.NoOverwrite}
 
 	// If ENV_ADMIN, import directly into their environment (skip creation)
 	if cfg.KeyType == "ENV_ADMIN" {
@@ -265,29 +262,35 @@ func EnvImport(name, description, filePath string, overwrite bool) error {
 			return fmt.Errorf("environment ID not found in API key context")
 		}
 
-		// Set all variables directly (no environment creation needed)
-		for key, value := range envVars {
-			setResp, err := cl.Put(fmt.Sprintf("/variables/%s", key), map[string]interface{}{
-				"value": value,
-			})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to set %s: %v\n", key, err)
-				continue
-			}
-			setResp.Body.Close()
-			if setResp.StatusCode != 204 {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to set %s (status %d)\n", key, setResp.StatusCode)
-			}
+		current, err := fetchVariables(cl)
+		if err != nil {
+			return fmt.Errorf("failed to fetch current variables: %w", err)
+		}
+
+		p := plan.New(variableMap(current), variableMap(envVars), planOpts)
+		apply, err := reviewPlan(p, opts)
+		if err != nil || !apply {
+			return err
+		}
+
+		if opts.Atomic {
+			err = applyAtomic(cl, cfg, p)
+		} else {
+			err = applyPlan(cl, p)
+		}
+		if err != nil {
+			return err
 		}
 
-		fmt.Printf("Imported %d variables into environment!\n", len(envVars))
+		fmt.Printf("Imported into environment: %d added, %d changed, %d removed\n",
+			p.Count(plan.Add), p.Count(plan.Change), p.Count(plan.Remove))
 		return nil
 	}
 
 	// APP_ADMIN: create environment and import (existing logic)
 	var envAdminKey string
 
-	if overwrite {
+	if opts.Overwrite {
 		// Try to find existing environment
 		resp, err := cl.Get("/environments")
 		if err == nil && resp.StatusCode == 200 {
@@ -305,7 +308,7 @@ func EnvImport(name, description, filePath string, overwrite bool) error {
 						fmt.Printf("Environment '%s' already exists. To import variables:\n", name)
 						fmt.Printf("  1. Run: not-env use\n")
 						fmt.Printf("  2. Enter the ENV_ADMIN key for '%s'\n", name)
-						fmt.Printf("  3. Run: not-env env import --name %s --file %s --overwrite\n", name, filePath)
+						fmt.Printf("  3. Run: not-env env import --name %s %s --overwrite\n", name, src.flag())
 						return fmt.Errorf("environment exists - use ENV_ADMIN key to import")
 					}
 				}
@@ -313,7 +316,52 @@ func EnvImport(name, description, filePath string, overwrite bool) error {
 		}
 	}
 
+	// A new environment has no variables, so everything is added
+	p := plan.New(nil, variableMap(envVars), planOpts)
+	fmt.Printf("Environment '%s' will be created.\n", name)
+	apply, err := reviewPlan(p, opts)
+	if err != nil || !apply {
+		return err
+	}
+
 	// Create new environment
+	created, err := createEnvironment(cl, name, description)
+	if err != nil {
+		return err
+	}
+
+	envAdminKey = created.Keys.EnvAdmin
+	envReadOnlyKey := created.Keys.EnvReadOnly
+
+	// Switch to ENV_ADMIN key for setting variables
+	cl = client.NewClient(cfg.URL, envAdminKey)
+
+	// Set all variables; the keys are printed even if some fail so the
+	// environment remains usable
+	applyErr := applyPlan(cl, p)
+
+	fmt.Printf("Environment '%s' created and populated with %d variables!\n", name, p.Count(plan.Add))
+	fmt.Printf("ENV_ADMIN key: %s\n", envAdminKey)
+	fmt.Printf("ENV_READ_ONLY key: %s\n", envReadOnlyKey)
+	fmt.Println("\nSave these keys securely!")
+	fmt.Println("Use ENV_ADMIN for managing variables (CLI)")
+	fmt.Println("Use ENV_READ_ONLY for applications (SDKs)")
+
+	return applyErr
+}
+
+// createdEnvironment is the backend's response to creating an environment
+type createdEnvironment struct {
+	ID   int64 `json:"id"`
+	Keys struct {
+		EnvAdmin    string `json:"env_admin"`
+		EnvReadOnly string `json:"env_read_only"`
+	} `json:"keys"`
+}
+
+// createEnvironment creates an environment with an APP_ADMIN client and
+// returns its ID and keys
+func createEnvironment(cl *client.Client, name, description string) (*createdEnvironment, error) {
 	reqBody := map[string]interface{}{
 		"name": name,
 	}
@@ -323,54 +371,57 @@ func EnvImport(name, description, filePath string, overwrite bool) error {
 
 	resp, err := cl.Post("/environments", reqBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if resp.StatusCode != 200 {
-		return client.ParseResponse(resp, nil)
+		return nil, client.ParseResponse(resp, nil)
 	}
 
-	var createResult struct {
-		ID   int64 `json:"id"`
-		Keys struct {
-			EnvAdmin    string `json:"env_admin"`
-			EnvReadOnly string `json:"env_read_only"`
-		} `json:"keys"`
+	var created createdEnvironment
+	if err := client.ParseResponse(resp, &created); err != nil {
+		return nil, err
 	}
+	return &created, nil
+}
 
-	if err := client.ParseResponse(resp, &createResult); err != nil {
-		return err
+// reviewPlan lets the user resolve conflicts (interactive imports), prints
+// the plan and reports whether it should be applied
+func reviewPlan(p *plan.Plan, opts ImportOptions) (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	if opts.Interactive && !opts.Plan {
+		if err := resolveConflicts(reader, p); err != nil {
+			return false, err
+		}
 	}
 
-	envAdminKey = createResult.Keys.EnvAdmin
-	envReadOnlyKey := createResult.Keys.EnvReadOnly
+	p.Print(os.Stdout, false)
 
-	// Switch to ENV_ADMIN key for setting variables
-	cl = client.NewClient(cfg.URL, envAdminKey)
+	if !opts.NoValidate {
+		if err := validatePlan(p); err != nil {
+			return false, err
+		}
+	}
 
-	// Set all variables
-	for key, value := range envVars {
-		setResp, err := cl.Put(fmt.Sprintf("/variables/%s", key), map[string]interface{}{
-			"value": value,
-		})
+	if opts.Plan {
+		return false, nil
+	}
+	if p.Empty() {
+		fmt.Println("Nothing to change.")
+		return false, nil
+	}
+	if opts.Interactive {
+		ok, err := confirm(reader, "Apply this plan?")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to set %s: %v\n", key, err)
-			continue
+			return false, err
 		}
-		setResp.Body.Close()
-		if setResp.StatusCode != 204 {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to set %s (status %d)\n", key, setResp.StatusCode)
+		if !ok {
+			fmt.Println("Import cancelled.")
+			return false, nil
 		}
 	}
-
-	fmt.Printf("Environment '%s' created and populated with %d variables!\n", name, len(envVars))
-	fmt.Printf("ENV_ADMIN key: %s\n", envAdminKey)
-	fmt.Printf("ENV_READ_ONLY key: %s\n", envReadOnlyKey)
-	fmt.Println("\nSave these keys securely!")
-	fmt.Println("Use ENV_ADMIN for managing variables (CLI)")
-	fmt.Println("Use ENV_READ_ONLY for applications (SDKs)")
-
-	return nil
+	return true, nil
 }
 
 // EnvShow shows current environment metadata
@@ -478,78 +529,115 @@ func EnvKeys() error {
 	return nil
 }
 
+// EnvSetOptions controls how EnvSet loads variables into the shell
+type EnvSetOptions struct {
+	LoadOptions
+
+	// SaveRestore records the values of overridden variables so that
+	// 'env clear --restore' can put them back
+	SaveRestore bool
+	// StateFile keeps the recorded values in a private temporary file
+	// instead of the exported NOT_ENV_STATE variable
+	StateFile bool
+}
+
 // EnvSet prints export commands for all variables
-func EnvSet() error {
-	cfg, err := config.Load()
-	if err != nil {
-		return err
+func EnvSet(opts EnvSetOptions) error {
+	if stdoutIsTerminal() {
+		fmt.Fprintln(os.Stderr, `Warning: printing secrets to the terminal; load them with eval "$(not-env env set)"`)
 	}
 
-	cl := client.NewClient(cfg.URL, cfg.APIKey)
-
-	resp, err := cl.Get("/variables")
+	variables, err := loadVariables(opts.LoadOptions)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode != 200 {
-		return client.ParseResponse(resp, nil)
+	var commands []string
+	for _, v := range variables {
+		commands = append(commands, shell.Bash.Export(v.Key, v.Value))
 	}
 
-	var result struct {
-		Variables []struct {
-			Key   string `json:"key"`
-			Value string `json:"value"`
-		} `json:"variables"`
+	if opts.SaveRestore {
+		stateCommands, err := saveRestoreState(variables, opts.StateFile)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, stateCommands...)
 	}
 
-	if err := client.ParseResponse(resp, &result); err != nil {
-		return err
+	fmt.Print(shell.Script(commands))
+	return nil
+}
+
+// saveRestoreState records the current values of the variables about to be
+// loaded and returns the commands that store the state in the shell.
+// Values already recorded by an earlier load are kept, so loading twice
+// still restores the original values.
+func saveRestoreState(variables []variable, toFile bool) ([]string, error) {
+	state, err := shell.LoadState()
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = &shell.State{}
 	}
 
-	for _, v := range result.Variables {
-		// Escape value for shell
-		escapedValue := strings.ReplaceAll(v.Value, `"`, `\"`)
-		escapedValue = strings.ReplaceAll(escapedValue, `$`, `\$`)
-		escapedValue = strings.ReplaceAll(escapedValue, "`", "\\`")
-		fmt.Printf("export %s=\"%s\"\n", v.Key, escapedValue)
+	environ := processEnviron()
+	for _, v := range variables {
+		state.Record(v.Key, environ)
 	}
 
-	return nil
-}
+	if toFile {
+		path := os.Getenv(shell.StateFileVar)
+		newPath, err := state.WriteFile()
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			os.Remove(path)
+		}
+		return []string{shell.Bash.Unset(shell.StateVar), shell.Bash.Export(shell.StateFileVar, newPath)}, nil
+	}
 
-// EnvClear prints unset commands for all variables
-func EnvClear() error {
-	cfg, err := config.Load()
+	encoded, err := state.Encode()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return []string{shell.Bash.Export(shell.StateVar, encoded)}, nil
+}
 
-	cl := client.NewClient(cfg.URL, cfg.APIKey)
-
-	resp, err := cl.Get("/variables")
+// EnvClear prints unset commands for the variables 'env set' loads with
+// opts and the project settings. When the shell has recorded state (from 'env set --save-restore' or the
+// directory hook), only the recorded variables are removed and the values
+// they shadowed are restored. With restore set, missing state is an error
+// rather than a reason to unset every remote key.
+func EnvClear(restore bool, opts LoadOptions) error {
+	state, err := shell.LoadState()
 	if err != nil {
 		return err
 	}
-
-	if resp.StatusCode != 200 {
-		return client.ParseResponse(resp, nil)
+	if state == nil {
+		if state, err = shell.LoadHookState(); err != nil {
+			return err
+		}
 	}
-
-	var result struct {
-		Variables []struct {
-			Key string `json:"key"`
-		} `json:"variables"`
+	if state != nil {
+		fmt.Print(shell.Script(shell.Bash.Restore(state)))
+		return nil
+	}
+	if restore {
+		return fmt.Errorf("no saved state found. Load variables with 'eval \"$(not-env env set --save-restore)\"' first")
 	}
 
-	if err := client.ParseResponse(resp, &result); err != nil {
+	// Unset the keys 'env set' with the same options would have exported
+	variables, err := loadVariables(opts)
+	if err != nil {
 		return err
 	}
 
-	for _, v := range result.Variables {
-		fmt.Printf("unset %s\n", v.Key)
+	for _, v := range variables {
+		fmt.Println(shell.Bash.Unset(v.Key))
 	}
 
 	return nil
 }
-
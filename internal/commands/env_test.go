@@ -2,8 +2,9 @@ package commands
 
 import (
 	"fmt"
-	"strings"
 	"testing"
+
+	"not-env-cli/internal/shell"
 )
 
 func TestEnvSetOutput(t *testing.T) {
@@ -20,15 +21,11 @@ func TestEnvSetOutput(t *testing.T) {
 		{key: "WITH_QUOTES", value: `"quoted"`, want: `export WITH_QUOTES="\"quoted\""`},
 		{key: "WITH_DOLLAR", value: "$VAR", want: `export WITH_DOLLAR="\$VAR"`},
 		{key: "WITH_BACKTICK", value: "`command`", want: "export WITH_BACKTICK=\"\\`command\\`\""},
+		{key: "WITH_BACKSLASH", value: `C:\path\`, want: `export WITH_BACKSLASH="C:\\path\\"`},
 	}
 
 	for _, tc := range testCases {
-		// Simulate the escaping logic from env.go
-		escapedValue := tc.value
-		escapedValue = strings.ReplaceAll(escapedValue, `"`, `\"`)
-		escapedValue = strings.ReplaceAll(escapedValue, `$`, `\$`)
-		escapedValue = strings.ReplaceAll(escapedValue, "`", "\\`")
-		output := fmt.Sprintf(`export %s="%s"`, tc.key, escapedValue)
+		output := shell.Bash.Export(tc.key, tc.value)
 
 		if output != tc.want {
 			t.Errorf("escapeValue(%q, %q) = %q, want %q", tc.key, tc.value, output, tc.want)
@@ -46,4 +43,3 @@ func TestEnvClearOutput(t *testing.T) {
 		t.Errorf("env clear output = %q, want %q", output, expected)
 	}
 }
-
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"not-env-cli/internal/dotenv"
+	"not-env-cli/internal/filter"
+)
+
+// environDenylist matches process environment variables that describe the
+// shell, terminal or session rather than an application's configuration
+var environDenylist = []string{
+	"_", "PATH", "HOME", "PWD", "OLDPWD", "SHLVL", "SHELL", "USER", "LOGNAME",
+	"HOSTNAME", "MAIL", "TERM", "TERM_*", "COLORTERM", "TMPDIR", "LANG",
+	"LANGUAGE", "LC_*", "TZ", "LS_COLORS", "PS1", "PS2", "PS4", "PROMPT_COMMAND",
+	"HIST*", "EDITOR", "VISUAL", "PAGER", "LESS*", "MANPATH", "INFOPATH",
+	"DISPLAY", "WAYLAND_DISPLAY", "XDG_*", "DBUS_*", "SSH_*", "GPG_*", "SUDO_*",
+	"TMUX", "TMUX_*", "STY", "WINDOWID", "SHELL_SESSION_*", "ITERM_*", "VSCODE_*",
+	"__CF*", "NOT_ENV_*", "_NOT_ENV_*",
+}
+
+// readEnviron returns the process environment without denylisted
+// variables and names that are not valid keys (such as bash's exported
+// functions, BASH_FUNC_name%%), optionally only the keys with prefix
+func readEnviron(prefix string, deny []string) ([]variable, error) {
+	patterns := append(append([]string{}, environDenylist...), deny...)
+
+	project, err := currentProject()
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		patterns = append(patterns, project.Import.EnvDeny...)
+	}
+	if err := (filter.Options{Exclude: patterns}).Validate(); err != nil {
+		return nil, err
+	}
+
+	var variables []variable
+	denied := 0
+	for key, value := range processEnviron() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if filter.MatchAny(patterns, key) || !dotenv.ValidKey(key) {
+			denied++
+			continue
+		}
+		variables = append(variables, variable{Key: key, Value: value})
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Key < variables[j].Key
+	})
+
+	if denied > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d shell and session variables (denylist and invalid names)\n", denied)
+	}
+	return variables, nil
+}
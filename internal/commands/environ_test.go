@@ -0,0 +1,32 @@
+package commands
+
+import "testing"
+
+func TestReadEnviron(t *testing.T) {
+	t.Setenv("SHLVL", "1")
+	t.Setenv("TESTAPP_HOST", "db")
+	t.Setenv("TESTAPP_TOKEN", "secret")
+	t.Setenv("TESTAPP_LC_X", "kept")
+	t.Setenv("TESTAPP_FUNC_f%%", "() {  echo; }")
+	t.Setenv("TESTAPP_A.B", "dotted")
+
+	variables, err := readEnviron("TESTAPP_", []string{"*_TOKEN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := variableMap(variables)
+	if len(got) != 2 || got["TESTAPP_HOST"] != "db" || got["TESTAPP_LC_X"] != "kept" {
+		t.Errorf("unexpected variables: %v", got)
+	}
+
+	variables, err = readEnviron("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range variables {
+		if v.Key == "SHLVL" || v.Key == "PATH" {
+			t.Errorf("denylisted variable %s imported", v.Key)
+		}
+	}
+}
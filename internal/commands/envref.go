@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+)
+
+// EnvRef names a set of variables on the command line: a profile, a stored
+// environment (found by name among the saved credentials) or a local file.
+type EnvRef struct {
+	Profile string
+	Env     string
+	File    string
+}
+
+// ParseEnvRef parses "profile:NAME", "env:NAME" or "file:PATH". Without a
+// prefix, an existing file (or a path containing a slash) is a file, a
+// saved profile is a profile and anything else an environment name.
+func ParseEnvRef(spec string) (EnvRef, error) {
+	kind, value, ok := strings.Cut(spec, ":")
+	if ok && value != "" {
+		switch kind {
+		case "profile":
+			return EnvRef{Profile: value}, nil
+		case "env":
+			return EnvRef{Env: value}, nil
+		case "file":
+			return EnvRef{File: value}, nil
+		}
+	}
+
+	if spec == "" {
+		return EnvRef{}, fmt.Errorf("empty environment reference")
+	}
+	if _, err := os.Stat(spec); err == nil || strings.ContainsRune(spec, '/') {
+		return EnvRef{File: spec}, nil
+	}
+	if cfg, err := config.LoadFile(); err == nil {
+		if _, ok := cfg.Profiles[spec]; ok {
+			return EnvRef{Profile: spec}, nil
+		}
+	}
+	return EnvRef{Env: spec}, nil
+}
+
+func (r EnvRef) String() string {
+	switch {
+	case r.File != "":
+		return r.File
+	case r.Profile != "":
+		return r.Profile
+	default:
+		return r.Env
+	}
+}
+
+// config returns the credentials of a profile or stored environment
+func (r EnvRef) config() (*config.Config, error) {
+	if r.File != "" {
+		return nil, fmt.Errorf("%s is a file, not an environment", r.File)
+	}
+
+	cfg, err := config.LoadFile()
+	if err != nil {
+		return nil, err
+	}
+	if r.Profile != "" {
+		return cfg.WithProfile(r.Profile)
+	}
+	return findEnvironment(cfg, r.Env)
+}
+
+// client returns a client authenticated for the referenced environment
+func (r EnvRef) client() (*client.Client, *config.Config, error) {
+	cfg, err := r.config()
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.NewClient(cfg.URL, cfg.APIKey), cfg, nil
+}
+
+// readEnvRef returns the variables of a profile, stored environment or file
+func readEnvRef(r EnvRef) ([]variable, error) {
+	if r.File != "" {
+		return readImportSource(ImportSource{File: r.File})
+	}
+
+	cl, _, err := r.client()
+	if err != nil {
+		return nil, err
+	}
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch variables of %s: %w", r, err)
+	}
+	return variables, nil
+}
+
+// findEnvironment returns the saved credentials (default or profile) whose
+// environment is called name. APP_ADMIN keys cannot read variables, so
+// they are skipped.
+func findEnvironment(cfg *config.Config, name string) (*config.Config, error) {
+	candidates := []*config.Config{cfg}
+
+	profiles := make([]string, 0, len(cfg.Profiles))
+	for p := range cfg.Profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Strings(profiles)
+	for _, p := range profiles {
+		resolved, err := cfg.WithProfile(p)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, resolved)
+	}
+
+	for _, c := range candidates {
+		if c.APIKey == "" || c.KeyType == "APP_ADMIN" {
+			continue
+		}
+		envName, err := environmentName(client.NewClient(c.URL, c.APIKey))
+		if err == nil && envName == name {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no saved key for environment '%s'. Run 'not-env login --profile %s' with its ENV_ADMIN or ENV_READ_ONLY key", name, name)
+}
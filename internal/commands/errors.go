@@ -0,0 +1,13 @@
+package commands
+
+import "fmt"
+
+// ExitError reports that the CLI should exit with Code without printing
+// an error message, e.g. to pass on a child process's exit status
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"not-env-cli/internal/atomicfile"
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/formats"
+)
+
+// EnvExport writes the environment's variables in the given format, sorted
+// by key, to stdout or (atomically, with permissions 0600) to output.
+// name is the metadata name of Kubernetes manifests and defaults to the
+// environment's name.
+func EnvExport(format, output, name string, opts LoadOptions) error {
+	variables, err := loadVariables(opts)
+	if err != nil {
+		return err
+	}
+
+	if name == "" && (format == formats.KubernetesSecret || format == formats.KubernetesConfigMap) {
+		name, err = currentEnvironmentName()
+		if err != nil {
+			return fmt.Errorf("failed to get environment name (set one with --name): %w", err)
+		}
+		// Kubernetes object names are lowercase DNS labels
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}
+
+	data, err := formats.Write(format, formatVars(variables), formats.WriteOptions{Name: name})
+	if err != nil {
+		return err
+	}
+
+	if output == "" || output == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	if err := atomicfile.Write(output, data); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d variables to %s\n", len(variables), output)
+	return nil
+}
+
+// currentEnvironmentName returns the name of the environment the active
+// key belongs to
+func currentEnvironmentName() (string, error) {
+	cl, err := profileClient("")
+	if err != nil {
+		return "", err
+	}
+	return environmentName(cl)
+}
+
+// environmentInfo is the metadata of an environment
+type environmentInfo struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// fetchEnvironment returns the metadata of the environment the client's
+// key belongs to
+func fetchEnvironment(cl *client.Client) (*environmentInfo, error) {
+	resp, err := cl.Get("/environment")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, client.ParseResponse(resp, nil)
+	}
+
+	var env environmentInfo
+	if err := client.ParseResponse(resp, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// environmentName returns the name of the environment the client's key
+// belongs to
+func environmentName(cl *client.Client) (string, error) {
+	env, err := fetchEnvironment(cl)
+	if err != nil {
+		return "", err
+	}
+	return env.Name, nil
+}
+
+// formatVars converts variables to the key-value pairs of package formats
+func formatVars(variables []variable) []formats.Var {
+	vars := make([]formats.Var, len(variables))
+	for i, v := range variables {
+		vars[i] = formats.Var{Key: v.Key, Value: v.Value}
+	}
+	return vars
+}
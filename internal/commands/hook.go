@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"not-env-cli/internal/atomicfile"
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/shell"
+)
+
+// hookCacheTTL is how long fetched variables are reused by the directory
+// hook before they are fetched again
+const hookCacheTTL = time.Minute
+
+// Hook prints the script that installs the directory hook for a shell
+func Hook(shellName string) error {
+	sh, err := shell.Parse(shellName)
+	if err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate not-env executable: %w", err)
+	}
+
+	fmt.Print(shell.Hook(sh, executable))
+	return nil
+}
+
+// HookExport prints the commands that load the variables of the project
+// in the working directory, unloading those of a previously loaded project
+// and restoring the values they shadowed.
+// It is called by the directory hook on each directory change.
+func HookExport(shellName string) error {
+	sh, err := shell.Parse(shellName)
+	if err != nil {
+		return err
+	}
+
+	// Variables loaded by 'env set --save-restore' are not managed by the
+	// hook; leave the shell alone until they are cleared
+	saved, err := shell.LoadState()
+	if err != nil {
+		return err
+	}
+	if saved != nil {
+		fmt.Fprintln(os.Stderr, "not-env: variables loaded with 'env set' are active; clear them with 'env clear --restore' to enable the hook")
+		return nil
+	}
+
+	state, err := shell.LoadHookState()
+	if err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	project, err := config.FindProject(wd)
+	if err != nil {
+		return err
+	}
+
+	if project == nil {
+		// Left the project: unload its variables
+		if state != nil {
+			fmt.Print(shell.Script(sh.Restore(state)))
+		}
+		return nil
+	}
+
+	info, err := os.Stat(project.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read project file: %w", err)
+	}
+	stamp := info.ModTime().UTC().Format(time.RFC3339Nano)
+
+	// Still inside the same, unchanged project: nothing to do
+	if state != nil && state.Dir == project.Dir() && state.Stamp == stamp {
+		return nil
+	}
+
+	variables, err := hookVariables(project)
+	if err != nil {
+		return err
+	}
+	variables, err = prepareVariables(variables, LoadOptions{}.withProject(project))
+	if err != nil {
+		return err
+	}
+
+	baseline := state.Baseline(processEnviron())
+	next := &shell.State{Dir: project.Dir(), Stamp: stamp}
+
+	var commands []string
+	loaded := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		next.Record(v.Key, baseline)
+		loaded[v.Key] = true
+		commands = append(commands, sh.Export(v.Key, v.Value))
+	}
+
+	// Restore variables of the previous project that are not reloaded
+	if state != nil {
+		for _, key := range state.Keys() {
+			if !loaded[key] {
+				commands = append(commands, sh.RestoreCommand(state, key))
+			}
+		}
+	}
+
+	encoded, err := next.Encode()
+	if err != nil {
+		return err
+	}
+	commands = append(commands, sh.SetLocal(shell.HookStateVar, encoded))
+
+	fmt.Print(shell.Script(commands))
+	return nil
+}
+
+// hookVariables returns the variables of the project's environment, using
+// a short-lived on-disk cache to keep the hook fast
+func hookVariables(project *config.Project) ([]variable, error) {
+	cfg, err := config.LoadFile()
+	if err != nil {
+		return nil, err
+	}
+	if project.Profile != "" {
+		cfg, err = cfg.WithProfile(project.Profile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(cfg.URL + "\x00" + cfg.APIKey))
+	cachePath := filepath.Join(hookCacheDir(), "hook-"+hex.EncodeToString(sum[:8])+".json")
+
+	var cached struct {
+		FetchedAt time.Time  `json:"fetched_at"`
+		Variables []variable `json:"variables"`
+	}
+	if data, err := os.ReadFile(cachePath); err == nil {
+		if json.Unmarshal(data, &cached) == nil && time.Since(cached.FetchedAt) < hookCacheTTL {
+			return cached.Variables, nil
+		}
+	}
+
+	variables, err := fetchVariables(client.NewClient(cfg.URL, cfg.APIKey))
+	if err != nil {
+		return nil, err
+	}
+
+	// Caching is best effort: a failure only costs speed
+	cached.FetchedAt = time.Now()
+	cached.Variables = variables
+	if data, err := json.Marshal(cached); err == nil {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err == nil {
+			atomicfile.Write(cachePath, data)
+		}
+	}
+
+	return variables, nil
+}
+
+// hookCacheDir returns the directory of the hook's variable cache
+func hookCacheDir() string {
+	return filepath.Join(config.GetConfigDir(), "cache")
+}
+
+// clearHookCache removes the hook's cached variables after a write, so the
+// next prompt fetches them again. Changes made from other machines are only
+// seen once the cache expires.
+func clearHookCache() {
+	paths, _ := filepath.Glob(filepath.Join(hookCacheDir(), "hook-*"))
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
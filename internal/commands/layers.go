@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+)
+
+// Layer is one source of variables in a layered load. At most one of
+// Profile and File is set; the zero Layer is the current environment.
+type Layer struct {
+	// Profile names a stored environment by the profile holding its key
+	Profile string
+	// File is a local .env file
+	File string
+}
+
+func (l Layer) String() string {
+	switch {
+	case l.File != "":
+		return fmt.Sprintf("%s (file)", l.File)
+	case l.Profile != "":
+		return fmt.Sprintf("%s (profile)", l.Profile)
+	case config.ActiveProfile() != "":
+		return fmt.Sprintf("%s (profile)", config.ActiveProfile())
+	}
+	return "current environment"
+}
+
+// layerValue is the value a layer gives to a key
+type layerValue struct {
+	Layer Layer
+	Value string
+}
+
+// provenance records, for each key, the values given by each layer in
+// load order; the last one is the effective value
+type provenance map[string][]layerValue
+
+// profileClient returns a client using the credentials of the named
+// profile, or of the active configuration if name is empty
+func profileClient(name string) (*client.Client, error) {
+	if name == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		return client.NewClient(cfg.URL, cfg.APIKey), nil
+	}
+
+	cfg, err := config.LoadFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = cfg.WithProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewClient(cfg.URL, cfg.APIKey), nil
+}
+
+// fetchLayer returns the variables of a single layer
+func fetchLayer(layer Layer) ([]variable, error) {
+	if layer.File == "" {
+		cl, err := profileClient(layer.Profile)
+		if err != nil {
+			return nil, err
+		}
+		variables, err := fetchVariables(cl)
+		if err != nil && layer.Profile != "" {
+			return nil, fmt.Errorf("layer %s: %w", layer, err)
+		}
+		return variables, err
+	}
+
+	variables, err := readEnvFile(layer.File, false)
+	if err != nil {
+		return nil, fmt.Errorf("layer %s: %w", layer, err)
+	}
+	return variables, nil
+}
+
+// mergeLayers loads layers in order, later layers overriding earlier ones.
+// Without any profile layer, the current environment is the base layer.
+// Keys keep the position of their first definition.
+func mergeLayers(layers []Layer) ([]variable, provenance, error) {
+	hasEnvironment := false
+	for _, layer := range layers {
+		if layer.File == "" {
+			hasEnvironment = true
+		}
+	}
+	if !hasEnvironment {
+		layers = append([]Layer{{}}, layers...)
+	}
+
+	var merged []variable
+	index := make(map[string]int)
+	sources := make(provenance)
+	for _, layer := range layers {
+		variables, err := fetchLayer(layer)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, v := range variables {
+			sources[v.Key] = append(sources[v.Key], layerValue{Layer: layer, Value: v.Value})
+			if pos, ok := index[v.Key]; ok {
+				merged[pos].Value = v.Value
+				continue
+			}
+			index[v.Key] = len(merged)
+			merged = append(merged, v)
+		}
+	}
+
+	return merged, sources, nil
+}
+
+// Explain prints which layer each effective value comes from and which
+// layers it shadows. With an empty key, every variable is explained.
+// Values are masked on a terminal unless reveal or the profile's reveal
+// setting is set.
+func Explain(key string, layers []Layer, reveal bool) error {
+	variables, sources, err := mergeLayers(layers)
+	if err != nil {
+		return err
+	}
+
+	// Layers may all be files, so explaining works without credentials
+	if cfg, err := config.Load(); err == nil {
+		reveal = revealValues(cfg, reveal)
+	} else {
+		reveal = reveal || !stdoutIsTerminal()
+	}
+
+	if key != "" {
+		if _, ok := sources[key]; !ok {
+			return fmt.Errorf("variable %s is not defined in any layer", key)
+		}
+		variables = []variable{{Key: key}}
+	}
+
+	for i, v := range variables {
+		if i > 0 {
+			fmt.Println()
+		}
+		values := sources[v.Key]
+		effective := values[len(values)-1]
+		fmt.Printf("%s=%s\n", v.Key, displayValue(effective.Value, reveal))
+		fmt.Printf("  from: %s\n", effective.Layer)
+		for j := len(values) - 2; j >= 0; j-- {
+			fmt.Printf("  shadows: %s: %s\n", values[j].Layer, displayValue(values[j].Value, reveal))
+		}
+	}
+
+	return nil
+}
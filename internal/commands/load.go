@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"os"
+
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/expand"
+	"not-env-cli/internal/filter"
+)
+
+// LoadOptions controls which variables are loaded from an environment and
+// under which names
+type LoadOptions struct {
+	// Layers are merged in order, later layers winning. Empty means the
+	// current environment only.
+	Layers []Layer
+
+	Filter filter.Options
+
+	// Expand resolves ${VAR} references between variables
+	Expand bool
+	// ExpandEnv lets references fall back to the process environment
+	ExpandEnv bool
+}
+
+// withProject returns opts applied on top of the project's load settings
+func (opts LoadOptions) withProject(project *config.Project) LoadOptions {
+	if project == nil {
+		return opts
+	}
+	merged := opts
+	merged.Filter = project.Load.Options.Merge(opts.Filter)
+	merged.Expand = opts.Expand || opts.ExpandEnv || project.Load.Expand
+	return merged
+}
+
+// loadVariables fetches the variables of the current environment and
+// applies opts on top of the settings of the project in the working
+// directory
+func loadVariables(opts LoadOptions) ([]variable, error) {
+	variables, _, err := mergeLayers(opts.Layers)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := currentProject()
+	if err != nil {
+		return nil, err
+	}
+
+	return prepareVariables(variables, opts.withProject(project))
+}
+
+// prepareVariables resolves references and then selects and renames
+// variables according to opts. References use the stored keys, so a
+// loaded variable may refer to one that is filtered out.
+func prepareVariables(variables []variable, opts LoadOptions) ([]variable, error) {
+	if opts.Expand || opts.ExpandEnv {
+		var err error
+		variables, err = expandVariables(variables, opts.ExpandEnv)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filterVariables(variables, opts.Filter)
+}
+
+// currentProject returns the project the working directory belongs to, or
+// nil if there is none
+func currentProject() (*config.Project, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return config.FindProject(wd)
+}
+
+// expandVariables resolves ${VAR} references between variables, falling
+// back to the process environment if allowEnv is set
+func expandVariables(variables []variable, allowEnv bool) ([]variable, error) {
+	values := make(map[string]string, len(variables))
+	for _, v := range variables {
+		values[v.Key] = v.Value
+	}
+
+	var environ map[string]string
+	if allowEnv {
+		environ = processEnviron()
+	}
+	expander := expand.New(values, environ)
+
+	expanded := make([]variable, len(variables))
+	for i, v := range variables {
+		value, err := expander.Get(v.Key)
+		if err != nil {
+			return nil, err
+		}
+		v.Value = value
+		expanded[i] = v
+	}
+	return expanded, nil
+}
+
+// filterVariables selects and renames variables according to opts
+func filterVariables(variables []variable, opts filter.Options) ([]variable, error) {
+	if opts.IsZero() {
+		return variables, nil
+	}
+
+	keys := make([]string, len(variables))
+	byKey := make(map[string]variable, len(variables))
+	for i, v := range variables {
+		keys[i] = v.Key
+		byKey[v.Key] = v
+	}
+
+	mappings, err := opts.Apply(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]variable, 0, len(mappings))
+	for _, m := range mappings {
+		v := byKey[m.Key]
+		v.Key = m.Name
+		filtered = append(filtered, v)
+	}
+	return filtered, nil
+}
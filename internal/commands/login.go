@@ -11,15 +11,22 @@ import (
 )
 
 // Login handles the login command
-// If url or apiKey are provided (non-empty), they will be used instead of prompting
+// If url or apiKey are provided (non-empty), they will be used instead of prompting.
+// When a profile is selected, the credentials are saved under that profile.
 func Login(url, apiKey string) error {
 	reader := bufio.NewReader(os.Stdin)
+	profile := config.ActiveProfile()
 
 	// Try to load existing config to get last used URL
 	var defaultURL string
-	existingConfig, err := config.Load()
-	if err == nil && existingConfig.URL != "" {
+	existingConfig, err := config.LoadFile()
+	if err == nil {
 		defaultURL = existingConfig.URL
+		if p, ok := existingConfig.Profiles[profile]; ok && p.URL != "" {
+			defaultURL = p.URL
+		}
+	} else {
+		existingConfig = &config.Config{}
 	}
 
 	// Get URL: use flag if provided, otherwise prompt
@@ -77,11 +84,6 @@ func Login(url, apiKey string) error {
 	}
 
 	// Get API key type from /me endpoint
-	cfg := &config.Config{
-		URL:    url,
-		APIKey: apiKey,
-	}
-
 	meResp, err := cl.Get("/me")
 	if err != nil {
 		return fmt.Errorf("failed to get API key info: %w", err)
@@ -100,14 +102,23 @@ func Login(url, apiKey string) error {
 		return fmt.Errorf("failed to parse API key info: %w", err)
 	}
 
-	cfg.KeyType = meInfo.KeyType
-	cfg.EnvIDFromKey = meInfo.EnvironmentID
+	// Keep other profiles when saving
+	existingConfig.SetProfileCredentials(profile, config.Profile{
+		URL:          url,
+		APIKey:       apiKey,
+		KeyType:      meInfo.KeyType,
+		EnvIDFromKey: meInfo.EnvironmentID,
+	})
 
-	if err := cfg.Save(); err != nil {
+	if err := existingConfig.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	fmt.Printf("Logged in successfully! (Key type: %s)\n", cfg.KeyType)
+	if profile != "" {
+		fmt.Printf("Logged in successfully! (Profile: %s, Key type: %s)\n", profile, meInfo.KeyType)
+		return nil
+	}
+	fmt.Printf("Logged in successfully! (Key type: %s)\n", meInfo.KeyType)
 	return nil
 }
 
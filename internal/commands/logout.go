@@ -6,8 +6,25 @@ import (
 	"not-env-cli/internal/config"
 )
 
-// Logout handles the logout command
+// Logout handles the logout command.
+// When a profile is selected, only that profile is removed.
 func Logout() error {
+	if profile := config.ActiveProfile(); profile != "" {
+		cfg, err := config.LoadFile()
+		if err != nil {
+			return err
+		}
+		if _, ok := cfg.Profiles[profile]; !ok {
+			return fmt.Errorf("profile '%s' not found", profile)
+		}
+		delete(cfg.Profiles, profile)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to logout: %w", err)
+		}
+		fmt.Printf("Logged out of profile %s successfully!\n", profile)
+		return nil
+	}
+
 	if err := config.Clear(); err != nil {
 		return fmt.Errorf("failed to logout: %w", err)
 	}
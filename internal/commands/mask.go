@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/plan"
+)
+
+// stdoutIsTerminal reports whether standard output is a terminal
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// revealValues reports whether values are printed in clear: when asked to
+// with --reveal or the profile's reveal setting, or when the output is not
+// a terminal (pipes and files get the real values)
+func revealValues(cfg *config.Config, reveal bool) bool {
+	return reveal || cfg.Reveal || !stdoutIsTerminal()
+}
+
+// maskValue hides a value behind its length and, for values of at least
+// plan.HashMinLength characters, a short hash, which is enough to tell
+// values apart without showing them
+func maskValue(value string) string {
+	n := utf8.RuneCountInString(value)
+	switch {
+	case n == 0:
+		return "(empty)"
+	case n < plan.HashMinLength:
+		return fmt.Sprintf("******** (%d chars)", n)
+	}
+	return fmt.Sprintf("******** (%d chars, %s)", n, plan.Hash(value))
+}
+
+// displayValue returns value, or its mask unless reveal is set
+func displayValue(value string, reveal bool) string {
+	if reveal {
+		return value
+	}
+	return maskValue(value)
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"not-env-cli/internal/plan"
+)
+
+func TestMaskValue(t *testing.T) {
+	masked := maskValue("hunter2")
+	if strings.Contains(masked, "hunter2") {
+		t.Errorf("value leaked: %s", masked)
+	}
+	if !strings.Contains(masked, "7 chars") {
+		t.Errorf("length missing: %s", masked)
+	}
+	if strings.Contains(masked, plan.Hash("hunter2")) {
+		t.Errorf("short value shows its hash: %s", masked)
+	}
+	long := "correct horse battery staple"
+	if !strings.Contains(maskValue(long), plan.Hash(long)) {
+		t.Errorf("long value does not show its hash: %s", maskValue(long))
+	}
+	if maskValue(long) == maskValue(long+"!") {
+		t.Error("different long values have the same mask")
+	}
+	if maskValue("") != "(empty)" {
+		t.Errorf("got %q for an empty value", maskValue(""))
+	}
+	if displayValue("hunter2", true) != "hunter2" {
+		t.Error("revealed value changed")
+	}
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"not-env-cli/internal/atomicfile"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/filter"
+	"not-env-cli/internal/plan"
+)
+
+// PromoteOptions controls how variables are promoted between environments
+type PromoteOptions struct {
+	// Keys selects the keys to promote (glob patterns, default all)
+	Keys []string
+	// Prune removes selected target keys that are not in the source
+	Prune bool
+	// Yes skips the confirmation of unprotected targets
+	Yes bool
+	// Confirm is the target name, confirming protected targets without a
+	// prompt
+	Confirm string
+	// Report is the path of the promotion report (default in
+	// ~/.not-env/promotions)
+	Report string
+}
+
+// promotionReport records what a promotion changed. Values are only
+// recorded as hashes.
+type promotionReport struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Time    string            `json:"time"`
+	Keys    []string          `json:"keys,omitempty"`
+	Changes []promotionChange `json:"changes"`
+}
+
+type promotionChange struct {
+	Key     string `json:"key"`
+	Action  string `json:"action"`
+	OldHash string `json:"old_hash,omitempty"`
+	NewHash string `json:"new_hash,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// EnvPromote copies the selected variables of from into to (see
+// ParseEnvRef; the two may use different profiles and backends). The plan
+// is shown and must be confirmed, by typing the target's name if it is
+// protected. A report of the applied changes is written afterwards.
+func EnvPromote(fromSpec, toSpec string, opts PromoteOptions) error {
+	from, err := ParseEnvRef(fromSpec)
+	if err != nil {
+		return err
+	}
+	to, err := ParseEnvRef(toSpec)
+	if err != nil {
+		return err
+	}
+	if to.File != "" {
+		return fmt.Errorf("cannot promote into a file (%s); use 'env export' instead", to.File)
+	}
+
+	sel := filter.Options{Only: opts.Keys}
+	if err := sel.Validate(); err != nil {
+		return err
+	}
+
+	source, err := readEnvRef(from)
+	if err != nil {
+		return err
+	}
+
+	targetClient, targetCfg, err := to.client()
+	if err != nil {
+		return err
+	}
+	if targetCfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("the saved key for %s is %s; promoting requires an ENV_ADMIN key", to, targetCfg.KeyType)
+	}
+	target, err := fetchVariables(targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to fetch variables of %s: %w", to, err)
+	}
+
+	source, err = filterVariables(source, sel)
+	if err != nil {
+		return err
+	}
+	target, err = filterVariables(target, sel)
+	if err != nil {
+		return err
+	}
+
+	p := plan.New(variableMap(target), variableMap(source), plan.Options{Prune: opts.Prune})
+	fmt.Printf("Promoting %s -> %s\n", from, to)
+	p.Print(os.Stdout, false)
+	if p.Empty() {
+		fmt.Println("Nothing to promote.")
+		return nil
+	}
+
+	ok, err := confirmPromotion(to, targetCfg, opts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Promotion cancelled.")
+		return nil
+	}
+
+	failed := applyItems(targetClient, p)
+
+	reportPath, err := writePromotionReport(from, to, opts, p, failed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write promotion report: %v\n", err)
+	} else {
+		fmt.Printf("Promotion report: %s\n", reportPath)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d changes failed", len(failed), p.Count(plan.Add)+p.Count(plan.Change)+p.Count(plan.Remove))
+	}
+	fmt.Printf("Promoted to %s: %d added, %d changed, %d removed\n",
+		to, p.Count(plan.Add), p.Count(plan.Change), p.Count(plan.Remove))
+	return nil
+}
+
+// confirmPromotion asks for confirmation; protected targets require their
+// name to be typed (or given with --confirm)
+func confirmPromotion(to EnvRef, cfg *config.Config, opts PromoteOptions) (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	if !cfg.Protected {
+		if opts.Yes {
+			return true, nil
+		}
+		return confirm(reader, fmt.Sprintf("Apply these changes to %s?", to))
+	}
+
+	if opts.Confirm != "" {
+		if opts.Confirm != to.String() {
+			return false, fmt.Errorf("--confirm %s does not match the target %s", opts.Confirm, to)
+		}
+		return true, nil
+	}
+
+	fmt.Printf("%s is protected. Type its name to confirm: ", to)
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != to.String() {
+		return false, fmt.Errorf("confirmation did not match %s; nothing was promoted", to)
+	}
+	return true, nil
+}
+
+// writePromotionReport writes the report of an applied promotion and
+// returns its path
+func writePromotionReport(from, to EnvRef, opts PromoteOptions, p *plan.Plan, failed map[string]error) (string, error) {
+	now := time.Now().UTC()
+	report := promotionReport{
+		From:    from.String(),
+		To:      to.String(),
+		Time:    now.Format(time.RFC3339),
+		Keys:    opts.Keys,
+		Changes: []promotionChange{},
+	}
+
+	for _, it := range p.Items {
+		if it.Action != plan.Add && it.Action != plan.Change && it.Action != plan.Remove {
+			continue
+		}
+		change := promotionChange{Key: it.Key, Action: string(it.Action), Status: "applied"}
+		if it.Action != plan.Add {
+			change.OldHash = plan.Fingerprint(it.Old)
+		}
+		if it.Action != plan.Remove {
+			change.NewHash = plan.Fingerprint(it.New)
+		}
+		if err := failed[it.Key]; err != nil {
+			change.Status = "failed"
+			change.Error = err.Error()
+		}
+		report.Changes = append(report.Changes, change)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := opts.Report
+	if path == "" {
+		dir := filepath.Join(config.GetConfigDir(), "promotions")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s-%s-to-%s.json", now.Format("20060102T150405Z"),
+			filepath.Base(from.String()), filepath.Base(to.String()))
+		path = filepath.Join(dir, name)
+	}
+
+	if err := atomicfile.Write(path, append(data, '\n')); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// PassphraseVar provides the passphrase of encrypted backups to scripts
+const PassphraseVar = "NOT_ENV_PASSPHRASE"
+
+// promptSecret reads a line from the terminal without echoing it. With
+// confirm, it is asked twice and both entries must match.
+func promptSecret(prompt string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for %s: stdin is not a terminal", prompt)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", prompt, err)
+	}
+
+	if confirm {
+		fmt.Fprintf(os.Stderr, "Confirm %s: ", prompt)
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", prompt, err)
+		}
+		if string(again) != string(secret) {
+			return "", fmt.Errorf("entries do not match")
+		}
+	}
+
+	return string(secret), nil
+}
+
+// readPassphrase returns the passphrase from NOT_ENV_PASSPHRASE or prompts
+// for it
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseVar); passphrase != "" {
+		return passphrase, nil
+	}
+
+	passphrase, err := promptSecret("passphrase", confirm)
+	if err != nil {
+		return "", fmt.Errorf("%w (or set %s)", err, PassphraseVar)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase cannot be empty")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"not-env-cli/internal/atomicfile"
+)
+
+// RenderOptions controls how a template is rendered
+type RenderOptions struct {
+	// Output is the file to write; empty or "-" writes to stdout
+	Output string
+	// AllowMissing renders missing keys as empty strings instead of failing
+	AllowMissing bool
+	// Load selects the variables available to the template
+	Load LoadOptions
+}
+
+// Render executes a text/template file ("-" for stdin) with the
+// environment's variables as data, e.g. {{ .DB_HOST }}. The output is
+// rendered completely before it is written, so a failing template never
+// leaves a partial file; files are written atomically with permissions
+// 0600.
+func Render(templatePath string, opts RenderOptions) error {
+	text, err := readInput(templatePath)
+	if err != nil {
+		return err
+	}
+
+	variables, err := loadVariables(opts.Load)
+	if err != nil {
+		return err
+	}
+
+	out, err := renderTemplate(filepath.Base(inputName(templatePath)), string(text), variableMap(variables), opts.AllowMissing)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == "" || opts.Output == "-" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := atomicfile.Write(opts.Output, out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Rendered %s to %s\n", inputName(templatePath), opts.Output)
+	return nil
+}
+
+// renderTemplate executes a template against values. Besides the keys as
+// fields it provides the following, which take the value last so that they
+// work at the end of a pipeline ({{ .PORT | default "5432" }}):
+//
+//	required "MSG" VALUE     VALUE, failing with MSG if it is empty
+//	default "DEFAULT" VALUE  VALUE, or DEFAULT if it is empty
+//	b64dec                   base64-decodes a value
+//	json                     encodes a value as a JSON string
+//	quote                    quotes a value with Go string escapes
+func renderTemplate(name, text string, values map[string]string, allowMissing bool) ([]byte, error) {
+	funcs := template.FuncMap{
+		"required": func(msg, value string) (string, error) {
+			if value == "" {
+				return "", fmt.Errorf("required: %s", msg)
+			}
+			return value, nil
+		},
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+		"b64dec": func(value string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return "", fmt.Errorf("b64dec: %w", err)
+			}
+			return string(decoded), nil
+		},
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		"quote": strconv.Quote,
+	}
+
+	missing := "missingkey=error"
+	if allowMissing {
+		missing = "missingkey=zero"
+	}
+
+	tmpl, err := template.New(name).Option(missing).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
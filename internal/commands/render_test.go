@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	values := map[string]string{
+		"HOST":  "db.internal",
+		"PORT":  "",
+		"CERT":  "aGVsbG8=",
+		"LABEL": `say "hi"`,
+	}
+
+	tests := []struct {
+		text, want string
+	}{
+		{`server {{ .HOST }};`, `server db.internal;`},
+		{`{{ required "HOST is not set" .HOST }}`, `db.internal`},
+		{`{{ .HOST | required "HOST is not set" }}`, `db.internal`},
+		{`{{ default "5432" .PORT }}`, `5432`},
+		{`{{ .PORT | default "5432" }}`, `5432`},
+		{`{{ .HOST | default "localhost" }}`, `db.internal`},
+		{`{{ index . "MISSING" | default "x" | quote }}`, `"x"`},
+		{`{{ .CERT | b64dec }}`, `hello`},
+		{`{"label": {{ .LABEL | json }}}`, `{"label": "say \"hi\""}`},
+		{`{{ .LABEL | quote }}`, `"say \"hi\""`},
+	}
+	for _, tt := range tests {
+		got, err := renderTemplate("t", tt.text, values, false)
+		if err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTemplateMissingKeys(t *testing.T) {
+	values := map[string]string{"PORT": ""}
+
+	for _, text := range []string{`{{ .MISSING }}`, `{{ .MISSING | default "x" }}`} {
+		if _, err := renderTemplate("t", text, values, false); err == nil {
+			t.Errorf("%s: expected an error", text)
+		}
+	}
+
+	_, err := renderTemplate("t", `{{ .PORT | required "PORT is not set" }}`, values, false)
+	if err == nil || !strings.Contains(err.Error(), "PORT is not set") {
+		t.Errorf("expected the required message, got %v", err)
+	}
+
+	got, err := renderTemplate("t", `[{{ .MISSING }}]`, values, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "[]" {
+		t.Errorf("got %q", got)
+	}
+
+	_, err = renderTemplate("t", `{{ .CERT | b64dec }}`, map[string]string{"CERT": "%%"}, false)
+	if err == nil || !strings.Contains(err.Error(), "b64dec") {
+		t.Errorf("expected a b64dec error, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// RunOptions controls how 'run' passes the variables to the command
+type RunOptions struct {
+	// Load selects the variables
+	Load LoadOptions
+	// AsFile lists keys whose values are written to private temporary
+	// files; the variables are set to the files' paths
+	AsFile []string
+	// FileVars keeps the AsFile variables and sets KEY_FILE to the paths
+	// instead
+	FileVars bool
+}
+
+// Run runs a command with the environment's variables added to its
+// environment, overriding variables of the same name.
+// Interrupt, termination, hangup and quit signals are forwarded to the
+// command, and its exit status is returned as an *ExitError. Files written
+// for AsFile keys are shredded when the command exits.
+func Run(args []string, opts RunOptions) error {
+	variables, err := loadVariables(opts.Load)
+	if err != nil {
+		return err
+	}
+
+	environ := processEnviron()
+	for _, v := range variables {
+		environ[v.Key] = v.Value
+	}
+
+	// Catch signals before writing files so that an interrupt still
+	// removes them
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+
+	if len(opts.AsFile) > 0 {
+		files, err := writeTempFiles(variableMap(variables), opts.AsFile)
+		if err != nil {
+			return err
+		}
+		defer files.remove()
+
+		for key, path := range files.paths {
+			if opts.FileVars {
+				environ[key+"_FILE"] = path
+			} else {
+				environ[key] = path
+			}
+		}
+	}
+
+	// A signal received while writing the files cancels the run instead of
+	// reaching the command once it starts
+	select {
+	case sig := <-signals:
+		fmt.Fprintf(os.Stderr, "not-env: %v, not starting %s\n", sig, args[0])
+		return &ExitError{Code: 128 + int(sig.(syscall.Signal))}
+	default:
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = make([]string, 0, len(environ))
+	for key, value := range environ {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			// Terminated by a signal: report it like shells do
+			code = 128 + int(status.Signal())
+		} else if code < 0 {
+			code = 1
+		}
+		return &ExitError{Code: code}
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"filippo.io/age"
+
+	"not-env-cli/internal/agecrypt"
+	"not-env-cli/internal/atomicfile"
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/dotenv"
+	"not-env-cli/internal/filter"
+)
+
+// shareVersion is the version of the share bundle format
+const shareVersion = 1
+
+// IdentityFileName is the private key file in the config directory used to
+// decrypt shared bundles
+const IdentityFileName = "identity"
+
+// shareBundle is the plaintext of an encrypted share bundle
+type shareBundle struct {
+	Version   int               `json:"version"`
+	From      string            `json:"from"`
+	CreatedAt string            `json:"created_at"`
+	Variables map[string]string `json:"variables"`
+}
+
+// defaultIdentityPath returns the path of the private key created by
+// ShareKeygen
+func defaultIdentityPath() string {
+	return filepath.Join(config.GetConfigDir(), IdentityFileName)
+}
+
+// Share writes the selected variables of the current environment as an
+// ASCII-armored bundle encrypted to the recipients' age public keys, to
+// output or stdout
+func Share(to, keys []string, output string) error {
+	if len(to) == 0 {
+		return fmt.Errorf("at least one recipient (--to) is required")
+	}
+	recipients, err := agecrypt.ParseRecipients(to)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+
+	env, err := fetchEnvironment(cl)
+	if err != nil {
+		return err
+	}
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return err
+	}
+	variables, err = filterVariables(variables, filter.Options{Only: keys})
+	if err != nil {
+		return err
+	}
+	if len(variables) == 0 {
+		return fmt.Errorf("no variables match the selected keys")
+	}
+
+	sealed, err := sealBundle(shareBundle{
+		Version:   shareVersion,
+		From:      env.Name,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Variables: variableMap(variables),
+	}, recipients)
+	if err != nil {
+		return err
+	}
+
+	if output == "" || output == "-" {
+		if _, err := os.Stdout.Write(sealed); err != nil {
+			return err
+		}
+	} else if err := atomicfile.Write(output, sealed); err != nil {
+		return err
+	}
+
+	// Keep stdout for the bundle
+	fmt.Fprintf(os.Stderr, "Shared %d variables of %s with %d recipients\n", len(variables), env.Name, len(recipients))
+	return nil
+}
+
+// sealBundle encrypts a share bundle to the recipients, ASCII-armored
+func sealBundle(bundle shareBundle, recipients []age.Recipient) ([]byte, error) {
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	return agecrypt.Encrypt(data, true, recipients...)
+}
+
+// ShareKeygen creates the private key used to decrypt shared bundles and
+// prints its public key, which teammates pass to 'share --to'
+func ShareKeygen(force bool) error {
+	path := defaultIdentityPath()
+
+	if !force {
+		if identities, err := agecrypt.LoadIdentities(path); err == nil {
+			if x, ok := identities[0].(*age.X25519Identity); ok {
+				fmt.Println(x.Recipient().String())
+				fmt.Fprintf(os.Stderr, "Using existing key %s (--force creates a new one)\n", path)
+				return nil
+			}
+		} else if _, statErr := os.Stat(path); !errors.Is(statErr, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().UTC().Format(time.RFC3339), identity.Recipient(), identity)
+	if err := atomicfile.Write(path, []byte(data)); err != nil {
+		return err
+	}
+
+	fmt.Println(identity.Recipient().String())
+	fmt.Fprintf(os.Stderr, "Private key saved to %s; send the public key above to whoever shares with you\n", path)
+	return nil
+}
+
+// readShare decrypts a share bundle with the private key in identity (or
+// the default key) and returns its variables
+func readShare(path, identity string) ([]variable, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity == "" {
+		identity = defaultIdentityPath()
+	}
+	identities, err := agecrypt.LoadIdentities(identity)
+	if err != nil {
+		return nil, fmt.Errorf("%w (create a key with 'not-env share keygen' or pass --identity)", err)
+	}
+
+	plain, err := agecrypt.Decrypt(data, identities...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, fmt.Errorf("%s was not encrypted to the key in %s", inputName(path), identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle shareBundle
+	if err := json.Unmarshal(plain, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid share bundle: %w", err)
+	}
+	if bundle.Version != shareVersion {
+		return nil, fmt.Errorf("unsupported share bundle version %d", bundle.Version)
+	}
+
+	// The bundle is written by someone else; its keys end up in request
+	// paths, so they get the same check as imported files
+	var bad []string
+	for key := range bundle.Variables {
+		if !dotenv.ValidKey(key) {
+			bad = append(bad, fmt.Sprintf("  %q", key))
+		}
+	}
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return nil, invalidKeysError(inputName(path), bad)
+	}
+
+	fmt.Fprintf(os.Stderr, "Decrypted %d variables shared from %s on %s\n", len(bundle.Variables), bundle.From, bundle.CreatedAt)
+
+	variables := make([]variable, 0, len(bundle.Variables))
+	for key, value := range bundle.Variables {
+		variables = append(variables, variable{Key: key, Value: value})
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Key < variables[j].Key
+	})
+	return variables, nil
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// writeIdentity saves a new age identity to a file and returns it with the
+// file's path
+func writeIdentity(t *testing.T) (*age.X25519Identity, string) {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "identity")
+	if err := os.WriteFile(path, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return identity, path
+}
+
+func TestShareRoundTrip(t *testing.T) {
+	identity, identityPath := writeIdentity(t)
+	_, otherPath := writeIdentity(t)
+
+	seal := func(bundle shareBundle) string {
+		t.Helper()
+		sealed, err := sealBundle(bundle, []age.Recipient{identity.Recipient()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "bundle.age")
+		if err := os.WriteFile(path, sealed, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	bundle := seal(shareBundle{Version: shareVersion, From: "dev", Variables: map[string]string{"B": "2", "A": "1"}})
+	variables, err := readShare(bundle, identityPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variables) != 2 || variables[0] != (variable{Key: "A", Value: "1"}) || variables[1] != (variable{Key: "B", Value: "2"}) {
+		t.Errorf("readShare() = %+v", variables)
+	}
+
+	if _, err := readShare(bundle, otherPath); err == nil || !strings.Contains(err.Error(), "was not encrypted to the key") {
+		t.Errorf("wrong identity: got %v", err)
+	}
+
+	future := seal(shareBundle{Version: shareVersion + 1, Variables: map[string]string{"A": "1"}})
+	if _, err := readShare(future, identityPath); err == nil || !strings.Contains(err.Error(), "unsupported share bundle version") {
+		t.Errorf("unsupported version: got %v", err)
+	}
+
+	hostile := seal(shareBundle{Version: shareVersion, Variables: map[string]string{"OK": "1", "../x": "2", "A?b=c": "3"}})
+	_, err = readShare(hostile, identityPath)
+	if err == nil {
+		t.Fatal("expected an error for invalid keys")
+	}
+	for _, key := range []string{`"../x"`, `"A?b=c"`} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"not-env-cli/internal/atomicfile"
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/plan"
+)
+
+// snapshot holds the values an atomic import was about to change, so they
+// can be restored. A nil value means the key did not exist.
+type snapshot struct {
+	URL    string             `json:"url"`
+	EnvID  int64              `json:"env_id"`
+	Time   string             `json:"time"`
+	Values map[string]*string `json:"values"`
+}
+
+// snapshotPath returns the path of the last import's snapshot for the
+// environment of cfg
+func snapshotPath(cfg *config.Config) (string, error) {
+	if cfg.EnvIDFromKey == nil {
+		return "", fmt.Errorf("environment ID not found in API key context")
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", cfg.URL, *cfg.EnvIDFromKey)))
+	return filepath.Join(config.GetConfigDir(), "snapshots", "import-"+hex.EncodeToString(sum[:])[:8]+".json"), nil
+}
+
+// newSnapshot records the current value (or absence) of every key the plan
+// changes
+func newSnapshot(cfg *config.Config, p *plan.Plan) *snapshot {
+	snap := &snapshot{
+		URL:    cfg.URL,
+		EnvID:  *cfg.EnvIDFromKey,
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Values: make(map[string]*string),
+	}
+	for _, it := range p.Items {
+		switch it.Action {
+		case plan.Add:
+			snap.Values[it.Key] = nil
+		case plan.Change, plan.Remove:
+			old := it.Old
+			snap.Values[it.Key] = &old
+		}
+	}
+	return snap
+}
+
+// save writes the snapshot with permissions 0600
+func (s *snapshot) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, data)
+}
+
+// loadSnapshot reads a snapshot written by save
+func loadSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// restore sets the given keys back to their snapshot values, returning the
+// keys that could not be restored
+func (s *snapshot) restore(cl *client.Client, keys []string) map[string]error {
+	failed := make(map[string]error)
+	for _, key := range keys {
+		var err error
+		if value := s.Values[key]; value == nil {
+			err = deleteVariable(cl, key)
+		} else {
+			err = setVariable(cl, key, *value)
+		}
+		if err != nil {
+			failed[key] = err
+		}
+	}
+	return failed
+}
+
+// applyAtomic applies a plan after saving a snapshot of the keys it
+// touches. On the first failure or an interrupt, the keys changed so far
+// are restored; keys that cannot be restored are reported. The snapshot
+// stays on disk for 'env rollback' unless the rollback succeeded.
+func applyAtomic(cl *client.Client, cfg *config.Config, p *plan.Plan) error {
+	path, err := snapshotPath(cfg)
+	if err != nil {
+		return err
+	}
+	snap := newSnapshot(cfg, p)
+	if err := snap.save(path); err != nil {
+		return fmt.Errorf("failed to save snapshot, nothing was imported: %w", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var touched []string
+	var cause error
+	for _, it := range p.Items {
+		select {
+		case <-signals:
+			cause = errors.New("interrupted")
+		default:
+		}
+		if cause != nil {
+			break
+		}
+
+		switch it.Action {
+		case plan.Add, plan.Change:
+			err = setVariable(cl, it.Key, it.New)
+		case plan.Remove:
+			err = deleteVariable(cl, it.Key)
+		default:
+			continue
+		}
+		// A failed request may still have reached the backend, so the key
+		// is restored as well
+		touched = append(touched, it.Key)
+		if err != nil {
+			cause = fmt.Errorf("failed to %s %s: %w", it.Action, it.Key, err)
+		}
+	}
+
+	if cause == nil {
+		fmt.Printf("Snapshot saved to %s ('not-env env rollback' undoes this import)\n", path)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Import %v; rolling back %d keys...\n", cause, len(touched))
+	failed := snap.restore(cl, touched)
+	if len(failed) == 0 {
+		os.Remove(path)
+		return fmt.Errorf("import %w; all changes were rolled back", cause)
+	}
+
+	keys := make([]string, 0, len(failed))
+	for key := range failed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(os.Stderr, "Could not roll back %s: %v\n", key, failed[key])
+	}
+	return fmt.Errorf("import %w; %d keys could not be rolled back (retry with 'not-env env rollback', snapshot: %s)", cause, len(failed), path)
+}
+
+// EnvRollback restores the keys changed by the last atomic import into the
+// current environment to their previous values
+func EnvRollback(yes bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("invalid key type: %s (only ENV_ADMIN can roll back)", cfg.KeyType)
+	}
+
+	path, err := snapshotPath(cfg)
+	if err != nil {
+		return err
+	}
+	snap, err := loadSnapshot(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no import to roll back (only 'env import --atomic' keeps a snapshot)")
+	}
+	if err != nil {
+		return err
+	}
+
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return fmt.Errorf("failed to fetch current variables: %w", err)
+	}
+
+	// Compare only the snapshot's keys; those absent before are removed
+	current := make(map[string]string)
+	desired := make(map[string]string)
+	all := variableMap(variables)
+	for key, value := range snap.Values {
+		if v, ok := all[key]; ok {
+			current[key] = v
+		}
+		if value != nil {
+			desired[key] = *value
+		}
+	}
+
+	p := plan.New(current, desired, plan.Options{Prune: true})
+	fmt.Printf("Rolling back the import of %s\n", snap.Time)
+	p.Print(os.Stdout, false)
+	if p.Empty() {
+		fmt.Println("Nothing to roll back.")
+		return os.Remove(path)
+	}
+
+	if !yes {
+		ok, err := confirm(bufio.NewReader(os.Stdin), "Apply this rollback?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Rollback cancelled.")
+			return nil
+		}
+	}
+
+	if err := applyPlan(cl, p); err != nil {
+		return fmt.Errorf("rollback incomplete: %w (snapshot kept: %s)", err, path)
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Println("Rollback complete.")
+	return nil
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/plan"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	id := int64(7)
+	cfg := &config.Config{URL: "https://example.com", EnvIDFromKey: &id}
+	p := plan.New(
+		map[string]string{"CHANGED": "old", "REMOVED": "gone", "SAME": "x"},
+		map[string]string{"CHANGED": "new", "ADDED": "a", "SAME": "x"},
+		plan.Options{Prune: true},
+	)
+
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := newSnapshot(cfg, p).save(path); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := loadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snap.Values) != 3 {
+		t.Fatalf("expected 3 touched keys, got %v", snap.Values)
+	}
+	if snap.Values["ADDED"] != nil {
+		t.Errorf("added key should be recorded as absent")
+	}
+	if v := snap.Values["CHANGED"]; v == nil || *v != "old" {
+		t.Errorf("CHANGED = %v, want old", v)
+	}
+	if v := snap.Values["REMOVED"]; v == nil || *v != "gone" {
+		t.Errorf("REMOVED = %v, want gone", v)
+	}
+}
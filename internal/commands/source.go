@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"not-env-cli/internal/dotenv"
+	"not-env-cli/internal/filter"
+	"not-env-cli/internal/formats"
+)
+
+// ImportSource describes a file to import variables from
+type ImportSource struct {
+	// File is the path of the file, or "-" for stdin
+	File string
+	// Format is one of formats.ImportFormats; empty detects it from the
+	// file name and content (stdin defaults to dotenv)
+	Format string
+	// Separator joins nested keys of structured formats (default "_")
+	Separator string
+	// Service selects the service of a compose file
+	Service string
+	// Expand resolves ${VAR} references between the imported variables
+	Expand bool
+	// Share is an encrypted share bundle to import instead of File
+	Share string
+	// Identity is the private key file that decrypts Share (default
+	// ~/.not-env/identity)
+	Identity string
+	// FromEnv imports the process environment instead of File, without
+	// the variables matching the built-in denylist, the project's
+	// [import] env_deny patterns or EnvDeny
+	FromEnv bool
+	// EnvPrefix keeps only environment variables with this prefix
+	EnvPrefix string
+	// EnvDeny adds denylist patterns for FromEnv
+	EnvDeny []string
+	// Only keeps the keys matching at least one glob pattern
+	Only []string
+}
+
+// flag returns the command-line flag that selects the source
+func (src ImportSource) flag() string {
+	if src.Share != "" {
+		return "--from-share " + src.Share
+	}
+	if src.FromEnv {
+		return "--from-env"
+	}
+	return "--file " + src.File
+}
+
+// readImportSource reads the variables of an import source
+func readImportSource(src ImportSource) ([]variable, error) {
+	variables, err := readSource(src)
+	if err != nil || len(src.Only) == 0 {
+		return variables, err
+	}
+	return filterVariables(variables, filter.Options{Only: src.Only})
+}
+
+// readSource reads all variables of an import source
+func readSource(src ImportSource) ([]variable, error) {
+	if src.Share != "" {
+		return readShare(src.Share, src.Identity)
+	}
+	if src.FromEnv {
+		return readEnviron(src.EnvPrefix, src.EnvDeny)
+	}
+
+	data, err := readInput(src.File)
+	if err != nil {
+		return nil, err
+	}
+
+	format := src.Format
+	if format == "" {
+		format = formats.Detect(src.File, data)
+	}
+	if format == formats.Dotenv {
+		return parseEnvData(inputName(src.File), data, src.Expand)
+	}
+
+	vars, err := formats.Parse(format, data, formats.ParseOptions{
+		Separator: src.Separator,
+		Service:   src.Service,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", inputName(src.File), err)
+	}
+	if err := checkKeys(inputName(src.File), vars); err != nil {
+		return nil, err
+	}
+
+	variables := make([]variable, len(vars))
+	for i, v := range vars {
+		variables[i] = variable{Key: v.Key, Value: v.Value}
+	}
+
+	if src.Expand {
+		return expandVariables(variables, false)
+	}
+	return variables, nil
+}
+
+// checkKeys rejects keys that are not valid variable names, such as
+// nested keys with spaces or slashes, naming where each was found in the
+// file
+func checkKeys(name string, vars []formats.Var) error {
+	var bad []string
+	for _, v := range vars {
+		if !dotenv.ValidKey(v.Key) {
+			bad = append(bad, fmt.Sprintf("  %q (from %s)", v.Key, v.Source))
+		}
+	}
+	if len(bad) > 0 {
+		return invalidKeysError(name, bad)
+	}
+	return nil
+}
+
+// invalidKeysError reports the invalid keys found in name, one per line
+func invalidKeysError(name string, bad []string) error {
+	return fmt.Errorf("invalid keys in %s; use letters, digits and underscores, not starting with a digit:\n%s",
+		name, strings.Join(bad, "\n"))
+}
+
+// readInput reads a whole file, or stdin for "-"
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return data, nil
+}
+
+// inputName names a path given to readInput in messages
+func inputName(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return path
+}
+
+// readEnvFile parses a .env file ("-" for stdin) into variables
+func readEnvFile(filePath string, expandRefs bool) ([]variable, error) {
+	data, err := readInput(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvData(inputName(filePath), data, expandRefs)
+}
+
+// parseEnvData parses .env data into variables in file order. Repeated
+// keys are reported as warnings and the last value wins. With expandRefs
+// set, ${VAR} references between the variables are resolved.
+func parseEnvData(name string, data []byte, expandRefs bool) ([]variable, error) {
+	entries, err := dotenv.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s:\n%w", name, err)
+	}
+
+	for _, duplicate := range dotenv.Duplicates(entries) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", name, duplicate)
+	}
+	entries = dotenv.Dedupe(entries)
+
+	if expandRefs {
+		entries, err = dotenv.Expand(entries, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", name, err)
+		}
+	}
+
+	variables := make([]variable, len(entries))
+	for i, e := range entries {
+		variables[i] = variable{Key: e.Key, Value: e.Value}
+	}
+	return variables, nil
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSourceRejectsInvalidKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"ok": 1, "db": {"a/b": 2, "x?y": 3}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := readSource(ImportSource{File: path})
+	if err == nil {
+		t.Fatal("expected an error for invalid keys")
+	}
+	for _, want := range []string{`"db_a/b" (from db.a/b)`, `"db_x?y" (from db.x?y)`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s: %v", want, err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db": {"host": "x"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSource(ImportSource{File: path}); err != nil {
+		t.Errorf("valid keys rejected: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// tempFiles is a private directory of files holding secret values
+type tempFiles struct {
+	dir   string
+	paths map[string]string
+}
+
+// writeTempFiles writes the values of keys to files named after the keys
+// in a new directory only the current user can access (0700, files 0600)
+func writeTempFiles(values map[string]string, keys []string) (*tempFiles, error) {
+	dir, err := os.MkdirTemp("", "not-env-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	files := &tempFiles{dir: dir, paths: make(map[string]string, len(keys))}
+
+	for _, key := range keys {
+		value, ok := values[key]
+		if !ok {
+			files.remove()
+			return nil, fmt.Errorf("variable %s not found", key)
+		}
+
+		path := filepath.Join(dir, key)
+		if err := os.WriteFile(path, []byte(value), 0600); err != nil {
+			files.remove()
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		files.paths[key] = path
+	}
+	return files, nil
+}
+
+// remove shreds the files and removes the directory
+func (f *tempFiles) remove() {
+	for _, path := range f.paths {
+		shredFile(path)
+	}
+	os.RemoveAll(f.dir)
+}
+
+// shredFile overwrites a file with zeros before removing it, so its
+// contents do not linger in the file system's free blocks. Errors are
+// ignored; the file is removed in any case.
+func shredFile(path string) {
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		if info, err := f.Stat(); err == nil {
+			f.Write(make([]byte, info.Size()))
+			f.Sync()
+		}
+		f.Close()
+	}
+	os.Remove(path)
+}
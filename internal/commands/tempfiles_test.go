@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteTempFiles(t *testing.T) {
+	values := map[string]string{"CERT": "-----BEGIN-----\nabc\n", "OTHER": "x"}
+
+	files, err := writeTempFiles(values, []string{"CERT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(files.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("directory mode %v", info.Mode().Perm())
+	}
+
+	path := files.paths["CERT"]
+	info, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("file mode %v", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != values["CERT"] {
+		t.Errorf("got %q", data)
+	}
+	if _, ok := files.paths["OTHER"]; ok {
+		t.Error("wrote a file for an unselected key")
+	}
+
+	files.remove()
+	if _, err := os.Stat(files.dir); !os.IsNotExist(err) {
+		t.Errorf("directory not removed: %v", err)
+	}
+}
+
+func TestWriteTempFilesMissingKey(t *testing.T) {
+	if _, err := writeTempFiles(map[string]string{"A": "1"}, []string{"A", "B"}); err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+}
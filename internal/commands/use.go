@@ -10,15 +10,22 @@ import (
 	"not-env-cli/internal/config"
 )
 
-// Use switches to a different API key while keeping the same backend URL
+// Use switches to a different API key while keeping the same backend URL.
+// When a profile is selected, the key is saved under that profile.
 func Use() error {
 	// Load existing config to get URL
-	existingConfig, err := config.Load()
+	existingConfig, err := config.LoadFile()
 	if err != nil {
 		return fmt.Errorf("not logged in. Run 'not-env login' first to set backend URL")
 	}
 
-	if existingConfig.URL == "" {
+	profile := config.ActiveProfile()
+	url := existingConfig.URL
+	if p, ok := existingConfig.Profiles[profile]; ok && p.URL != "" {
+		url = p.URL
+	}
+
+	if url == "" {
 		return fmt.Errorf("no backend URL configured. Run 'not-env login' first")
 	}
 
@@ -35,7 +42,7 @@ func Use() error {
 	}
 
 	// Validate credentials by making a test request
-	cl := client.NewClient(existingConfig.URL, apiKey)
+	cl := client.NewClient(url, apiKey)
 	resp, err := cl.Get("/health")
 	if err != nil {
 		return fmt.Errorf("failed to connect to backend: %w", err)
@@ -65,18 +72,24 @@ func Use() error {
 		return fmt.Errorf("failed to parse API key info: %w", err)
 	}
 
-	// Update config with new API key (keep existing URL)
-	existingConfig.APIKey = apiKey
-	existingConfig.KeyType = meInfo.KeyType
-	existingConfig.EnvIDFromKey = meInfo.EnvironmentID
-	// Clear env_id since we're switching to a potentially different environment
-	existingConfig.EnvID = nil
+	// Update config with new API key (keep existing URL).
+	// This clears env_id since we're switching to a potentially different environment.
+	existingConfig.SetProfileCredentials(profile, config.Profile{
+		URL:          url,
+		APIKey:       apiKey,
+		KeyType:      meInfo.KeyType,
+		EnvIDFromKey: meInfo.EnvironmentID,
+	})
 
 	if err := existingConfig.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	fmt.Printf("Switched to new API key (backend: %s, key type: %s)\n", existingConfig.URL, existingConfig.KeyType)
+	if profile != "" {
+		fmt.Printf("Switched profile %s to new API key (backend: %s, key type: %s)\n", profile, url, meInfo.KeyType)
+		return nil
+	}
+	fmt.Printf("Switched to new API key (backend: %s, key type: %s)\n", url, meInfo.KeyType)
 	return nil
 }
 
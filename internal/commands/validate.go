@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"not-env-cli/internal/plan"
+	"not-env-cli/internal/schema"
+)
+
+// currentSchema returns the schema file of the working directory or its
+// parents, or nil if there is none
+func currentSchema() (*schema.Schema, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return schema.Find(wd)
+}
+
+// schemaError reports problems found in a schema's path
+func schemaError(s *schema.Schema, problems []schema.Problem) error {
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = "  " + p.Error()
+	}
+	return fmt.Errorf("values do not match %s:\n%s\n(pass --no-validate to skip the check)",
+		s.Path, strings.Join(lines, "\n"))
+}
+
+// validatePlan checks the values a plan writes against the schema, and
+// that it does not remove required keys
+func validatePlan(p *plan.Plan) error {
+	s, err := currentSchema()
+	if err != nil || s == nil {
+		return err
+	}
+
+	var problems []schema.Problem
+	for _, item := range p.Items {
+		switch item.Action {
+		case plan.Add, plan.Change:
+			if problem := s.CheckValue(item.Key, item.New); problem != nil {
+				problems = append(problems, *problem)
+			}
+		case plan.Remove:
+			if k, ok := s.Keys[item.Key]; ok && k.Required && k.Default == nil {
+				problems = append(problems, schema.Problem{Key: item.Key, Message: "required and would be removed"})
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return schemaError(s, problems)
+	}
+	return nil
+}
+
+// validateValue checks a single value against the schema
+func validateValue(key, value string) error {
+	s, err := currentSchema()
+	if err != nil || s == nil {
+		return err
+	}
+	if problem := s.CheckValue(key, value); problem != nil {
+		return schemaError(s, []schema.Problem{*problem})
+	}
+	return nil
+}
+
+// EnvValidate checks the current environment against the schema file:
+// required keys must exist and every declared key must have a value of its
+// type. Problems are reported with an *ExitError of code 1.
+func EnvValidate() error {
+	s, err := currentSchema()
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return fmt.Errorf("no %s found in this directory or its parents", schema.FileName)
+	}
+
+	cl, err := profileClient("")
+	if err != nil {
+		return err
+	}
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return err
+	}
+	values := variableMap(variables)
+
+	problems := s.Validate(values)
+	for _, p := range problems {
+		fmt.Printf("  %s\n", p.Error())
+	}
+
+	var defaulted []string
+	for key, k := range s.Keys {
+		if _, ok := values[key]; !ok && k.Default != nil {
+			defaulted = append(defaulted, key)
+		}
+	}
+	if len(defaulted) > 0 {
+		sort.Strings(defaulted)
+		fmt.Printf("Unset, using defaults: %s\n", strings.Join(defaulted, ", "))
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "Validation failed: %d problems\n", len(problems))
+		return &ExitError{Code: 1}
+	}
+	fmt.Printf("OK: %d declared keys valid\n", len(s.Keys))
+	return nil
+}
@@ -1,65 +1,55 @@
 package commands
 
 import (
+	"encoding/base64"
 	"fmt"
 
 	"not-env-cli/internal/client"
 	"not-env-cli/internal/config"
+	"not-env-cli/internal/expand"
 )
 
-// VarList lists all variables
-func VarList() error {
+// VarGet gets a single variable.
+// With resolved set, ${VAR} references in the value are resolved against
+// the environment's other variables (and the process environment if
+// expandEnv is set). The value is masked on a terminal unless reveal or
+// the profile's reveal setting is set.
+func VarGet(key string, resolved, expandEnv, reveal bool) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
 
 	cl := client.NewClient(cfg.URL, cfg.APIKey)
-
-	resp, err := cl.Get("/variables")
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		return client.ParseResponse(resp, nil)
-	}
-
-	var result struct {
-		Variables []struct {
-			Key       string `json:"key"`
-			Value     string `json:"value"`
-			CreatedAt string `json:"created_at"`
-			UpdatedAt string `json:"updated_at"`
-		} `json:"variables"`
-	}
-
-	if err := client.ParseResponse(resp, &result); err != nil {
-		return err
-	}
-
-	if len(result.Variables) == 0 {
-		fmt.Println("No variables found.")
+	reveal = revealValues(cfg, reveal)
+
+	if resolved {
+		variables, err := fetchVariables(cl)
+		if err != nil {
+			return err
+		}
+
+		values := make(map[string]string, len(variables))
+		for _, v := range variables {
+			values[v.Key] = v.Value
+		}
+		if _, ok := values[key]; !ok {
+			return fmt.Errorf("variable %s not found", key)
+		}
+
+		var environ map[string]string
+		if expandEnv {
+			environ = processEnviron()
+		}
+		value, err := expand.New(values, environ).Get(key)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s=%s\n", key, displayValue(value, reveal))
 		return nil
 	}
 
-	fmt.Println("Variables:")
-	for _, v := range result.Variables {
-		fmt.Printf("  %s=%s\n", v.Key, v.Value)
-	}
-
-	return nil
-}
-
-// VarGet gets a single variable
-func VarGet(key string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return err
-	}
-
-	cl := client.NewClient(cfg.URL, cfg.APIKey)
-
 	resp, err := cl.Get(fmt.Sprintf("/variables/%s", key))
 	if err != nil {
 		return err
@@ -80,12 +70,49 @@ func VarGet(key string) error {
 		return err
 	}
 
-	fmt.Printf("%s=%s\n", v.Key, v.Value)
+	fmt.Printf("%s=%s\n", v.Key, displayValue(v.Value, reveal))
 	return nil
 }
 
-// VarSet sets a variable
-func VarSet(key, value string) error {
+// VarSetOptions controls how 'var set' reads and writes a variable. At
+// most one of Stdin, FromFile, Prompt and FromCmd may be set; they replace
+// the value argument so that secrets stay out of shell history and ps.
+type VarSetOptions struct {
+	// NoValidate skips checking the value against the schema file
+	NoValidate bool
+	// Stdin reads the value from standard input
+	Stdin bool
+	// FromFile reads the value from a file
+	FromFile string
+	// Prompt asks for the value on the terminal without echo, twice
+	Prompt bool
+	// FromCmd reads the value from the output of a shell command
+	FromCmd string
+	// Base64 stores the value base64-encoded, e.g. for binary files
+	Base64 bool
+}
+
+// VarSet sets a variable, after checking the value against the schema
+// file if there is one. value is ignored if opts selects another input.
+func VarSet(key, value string, opts VarSetOptions) error {
+	switch modes := opts.inputModes(); {
+	case modes > 1:
+		return fmt.Errorf("only one of --stdin, --from-file, --prompt and --from-cmd can be used")
+	case modes == 1:
+		var err error
+		if value, err = readValue(key, opts); err != nil {
+			return err
+		}
+	case opts.Base64:
+		value = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	if !opts.NoValidate {
+		if err := validateValue(key, value); err != nil {
+			return err
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		return err
@@ -103,6 +130,7 @@ func VarSet(key, value string) error {
 	if resp.StatusCode != 204 {
 		return client.ParseResponse(resp, nil)
 	}
+	clearHookCache()
 
 	fmt.Printf("Variable %s set successfully!\n", key)
 	return nil
@@ -125,6 +153,7 @@ func VarDelete(key string) error {
 	if resp.StatusCode != 204 {
 		return client.ParseResponse(resp, nil)
 	}
+	clearHookCache()
 
 	fmt.Printf("Variable %s deleted successfully!\n", key)
 	return nil
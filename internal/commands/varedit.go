@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/formats"
+	"not-env-cli/internal/plan"
+)
+
+// editHeader explains the buffer opened by 'var edit'
+const editHeader = `# Variables of %s. Save and quit the editor to review the changes.
+# Add, change or delete lines to add, change or delete variables.
+# Lines starting with # are ignored.
+`
+
+// Phases of 'var edit', deciding what an interrupt does
+const (
+	editPhaseReview int32 = iota
+	editPhaseEditor
+	editPhaseApply
+)
+
+// VarEdit opens the variables of the environment as a dotenv file in
+// $VISUAL or $EDITOR, then shows the resulting plan and applies it after
+// confirmation. The file is private (0600) and shredded afterwards;
+// syntax errors and schema violations reopen the editor, and the edit is
+// refused if the variables changed on the server in the meantime.
+func VarEdit() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("var edit requires an ENV_ADMIN key")
+	}
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+
+	env, err := fetchEnvironment(cl)
+	if err != nil {
+		return err
+	}
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return err
+	}
+	current := variableMap(variables)
+
+	buffer, err := editBuffer(env.Name, variables)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "not-env-edit-*.env")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	defer shredFile(path)
+	_, err = f.Write(buffer)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	// Interrupts go to the editor while it runs and stop the changes after
+	// the current one while they are applied; otherwise they remove the
+	// file before exiting
+	var phase atomic.Int32
+	var interrupted atomic.Bool
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for range signals {
+			switch phase.Load() {
+			case editPhaseEditor:
+			case editPhaseApply:
+				interrupted.Store(true)
+				fmt.Fprintln(os.Stderr, "\nStopping after the current change...")
+			default:
+				shredFile(path)
+				fmt.Fprintln(os.Stderr, "\nEdit cancelled.")
+				os.Exit(130)
+			}
+		}
+	}()
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		phase.Store(editPhaseEditor)
+		err := runEditor(path)
+		phase.Store(editPhaseReview)
+		if err != nil {
+			return err
+		}
+
+		p, err := readEdit(path, current)
+		if err == nil && p.Empty() {
+			fmt.Println("No changes.")
+			return nil
+		}
+		if err == nil {
+			p.Print(os.Stdout, false)
+			err = validatePlan(p)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			again, err := confirm(reader, "Edit again?")
+			if err != nil {
+				return err
+			}
+			if again {
+				continue
+			}
+			fmt.Println("Edit cancelled.")
+			return nil
+		}
+
+		// The plan deletes everything missing from the file, so it must not
+		// be applied over changes made by someone else in the meantime
+		latest, err := fetchVariables(cl)
+		if err != nil {
+			return err
+		}
+		if !maps.Equal(current, variableMap(latest)) {
+			return fmt.Errorf("the variables of %s changed while you were editing; run 'var edit' again to edit the current values", env.Name)
+		}
+
+		ok, err := confirm(reader, "Apply these changes?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Edit cancelled.")
+			return nil
+		}
+
+		phase.Store(editPhaseApply)
+		total := p.Count(plan.Add) + p.Count(plan.Change) + p.Count(plan.Remove)
+		done, failed := 0, 0
+		for _, it := range p.Items {
+			if it.Action != plan.Add && it.Action != plan.Change && it.Action != plan.Remove {
+				continue
+			}
+			if interrupted.Load() {
+				break
+			}
+			if err := applyItem(cl, it); err != nil {
+				failed++
+			}
+			done++
+		}
+		phase.Store(editPhaseReview)
+
+		if interrupted.Load() {
+			fmt.Fprintf(os.Stderr, "Interrupted: %d of %d changes applied (%d failed); run 'var edit' again to review the rest\n",
+				done-failed, total, failed)
+			return &ExitError{Code: 130}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d changes failed", failed, total)
+		}
+		fmt.Printf("Applied: %d added, %d changed, %d deleted\n",
+			p.Count(plan.Add), p.Count(plan.Change), p.Count(plan.Remove))
+		return nil
+	}
+}
+
+// editBuffer renders variables as a commented dotenv file
+func editBuffer(envName string, variables []variable) ([]byte, error) {
+	data, err := formats.Write(formats.Dotenv, formatVars(variables), formats.WriteOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(fmt.Sprintf(editHeader, envName)), data...), nil
+}
+
+// readEdit parses the edited file and plans the changes to current.
+// Variables missing from the file are deleted.
+func readEdit(path string, current map[string]string) (*plan.Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	desired, err := parseEnvData(path, data, false)
+	if err != nil {
+		return nil, err
+	}
+	return plan.New(current, variableMap(desired), plan.Options{Prune: true}), nil
+}
+
+// editorCommand returns the user's editor: $VISUAL, $EDITOR or vi
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// runEditor opens path in the user's editor. The editor command may
+// include arguments, e.g. "code --wait".
+func runEditor(path string) error {
+	editor := editorCommand()
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"not-env-cli/internal/plan"
+)
+
+func TestEditBufferRoundTrip(t *testing.T) {
+	variables := []variable{
+		{Key: "PLAIN", Value: "value"},
+		{Key: "QUOTED", Value: `say "hi" $HOME`},
+		{Key: "MULTI", Value: "line1\nline2"},
+		{Key: "EMPTY", Value: ""},
+	}
+	current := variableMap(variables)
+
+	buffer, err := editBuffer("dev", variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "edit.env")
+	if err := os.WriteFile(path, buffer, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := readEdit(path, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Empty() {
+		t.Errorf("unedited buffer should not change anything: %s", p.Summary())
+	}
+
+	edited := append(buffer, []byte("ADDED=1\n")...)
+	if err := os.WriteFile(path, edited, 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err = readEdit(path, map[string]string{"PLAIN": "value", "GONE": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Count(plan.Remove) != 1 || p.Count(plan.Add) != 4 {
+		t.Errorf("unexpected plan: %s", p.Summary())
+	}
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	"not-env-cli/internal/client"
+)
+
+// variable is a single variable as returned by the backend
+type variable struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// fetchVariables fetches all variables of the environment the client's
+// API key belongs to
+func fetchVariables(cl *client.Client) ([]variable, error) {
+	resp, err := cl.Get("/variables")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, client.ParseResponse(resp, nil)
+	}
+
+	var result struct {
+		Variables []variable `json:"variables"`
+	}
+
+	if err := client.ParseResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Variables, nil
+}
+
+// processEnviron returns the current process environment as a map
+func processEnviron() map[string]string {
+	environ := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			environ[key] = value
+		}
+	}
+	return environ
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"unicode/utf8"
+)
+
+// MaxValueSize is the largest value 'var set' reads from stdin, a file or
+// a command
+const MaxValueSize = 1 << 20
+
+// inputModes counts the value inputs selected in opts
+func (opts VarSetOptions) inputModes() int {
+	n := 0
+	for _, set := range []bool{opts.Stdin, opts.FromFile != "", opts.Prompt, opts.FromCmd != ""} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// readValue reads the value of key from the input selected in opts. Files
+// are read byte for byte; a single trailing newline is removed from stdin
+// and command output, as a shell removes it from $(...).
+func readValue(key string, opts VarSetOptions) (string, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case opts.Prompt:
+		value, err := promptSecret("value of "+key, true)
+		if err != nil {
+			return "", err
+		}
+		data = []byte(value)
+	case opts.Stdin:
+		data, err = readLimited(os.Stdin, "stdin")
+		data = trimNewline(data)
+	case opts.FromFile != "":
+		var f *os.File
+		f, err = os.Open(opts.FromFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+		data, err = readLimited(f, opts.FromFile)
+	case opts.FromCmd != "":
+		data, err = commandOutput(opts.FromCmd)
+		data = trimNewline(data)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if opts.Base64 {
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("value of %s is binary data; pass --base64 to store it encoded", key)
+	}
+	return string(data), nil
+}
+
+// readLimited reads r completely, failing if it holds more than
+// MaxValueSize bytes
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxValueSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > MaxValueSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, MaxValueSize)
+	}
+	return data, nil
+}
+
+// commandOutput runs a shell command and returns its standard output. Its
+// standard error is passed through.
+func commandOutput(command string) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run %q: %w", command, err)
+	}
+	data, readErr := readLimited(stdout, "command output")
+	if readErr != nil {
+		cmd.Process.Kill()
+	}
+	if err := cmd.Wait(); err != nil && readErr == nil {
+		return nil, fmt.Errorf("command %q failed: %w", command, err)
+	}
+	return data, readErr
+}
+
+// trimNewline removes one trailing newline (\n or \r\n)
+func trimNewline(data []byte) []byte {
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		return data
+	}
+	data = data[:len(data)-1]
+	return bytes.TrimSuffix(data, []byte("\r"))
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadValueFromFile(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(text, []byte("line1\nline2\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	binary := filepath.Join(dir, "key.der")
+	if err := os.WriteFile(binary, []byte{0x30, 0x82, 0xff, 0x00}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are read byte for byte
+	value, err := readValue("CERT", VarSetOptions{FromFile: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "line1\nline2\n\n" {
+		t.Errorf("got %q", value)
+	}
+
+	if _, err := readValue("KEY", VarSetOptions{FromFile: binary}); err == nil {
+		t.Error("expected binary data to be refused")
+	}
+	value, err = readValue("KEY", VarSetOptions{FromFile: binary, Base64: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "MIL/AA==" {
+		t.Errorf("got %q", value)
+	}
+}
+
+func TestReadValueFromCmd(t *testing.T) {
+	value, err := readValue("TOKEN", VarSetOptions{FromCmd: "printf 'abc\\n\\n'"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "abc\n" {
+		t.Errorf("got %q", value)
+	}
+
+	if _, err := readValue("TOKEN", VarSetOptions{FromCmd: "exit 3"}); err == nil {
+		t.Error("expected a failing command to fail")
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), MaxValueSize)
+	if _, err := readLimited(bytes.NewReader(data), "input"); err != nil {
+		t.Fatal(err)
+	}
+
+	data = append(data, 'x')
+	_, err := readLimited(bytes.NewReader(data), "input")
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("expected a size error, got %v", err)
+	}
+}
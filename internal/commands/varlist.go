@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/filter"
+)
+
+// ListFormats lists the output formats of 'var list'
+var ListFormats = []string{"text", "table", "json", "yaml", "csv"}
+
+// ListColumns lists the columns 'var list' can show
+var ListColumns = []string{"key", "value", "created", "updated", "size"}
+
+// VarListOptions controls what 'var list' shows and how
+type VarListOptions struct {
+	// Format is one of ListFormats; empty means text (KEY=VALUE lines), or
+	// table if Columns is set
+	Format string
+	// Columns selects and orders the columns; empty shows key and value in
+	// a table and all columns in the other formats. The text format has no
+	// columns.
+	Columns []string
+	// Sort orders by "key" (the default) or "updated" (newest first)
+	Sort string
+	// Filter only lists keys matching a glob pattern
+	Filter string
+	// Regex only lists keys matching a regular expression
+	Regex string
+	// LocalTime shows timestamps in local time instead of relative to now
+	// (table) or in UTC (other formats)
+	LocalTime bool
+	// Reveal shows values on a terminal instead of masking them
+	Reveal bool
+}
+
+// VarList lists the variables. Values are masked on a terminal unless
+// opts.Reveal or the profile's reveal setting is set.
+func VarList(opts VarListOptions) error {
+	if opts.Format == "" {
+		opts.Format = "text"
+		if len(opts.Columns) > 0 {
+			opts.Format = "table"
+		}
+	}
+	if !slices.Contains(ListFormats, opts.Format) {
+		return fmt.Errorf("unknown format %q (expected one of %s)", opts.Format, strings.Join(ListFormats, ", "))
+	}
+	columns, err := listColumns(opts)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cl := client.NewClient(cfg.URL, cfg.APIKey)
+
+	variables, err := fetchVariables(cl)
+	if err != nil {
+		return err
+	}
+	variables, err = selectVariables(variables, opts)
+	if err != nil {
+		return err
+	}
+
+	opts.Reveal = revealValues(cfg, opts.Reveal)
+
+	switch opts.Format {
+	case "json", "yaml":
+		return printStructured(variables, columns, opts)
+	case "csv":
+		return printCSV(variables, columns, opts)
+	}
+
+	if len(variables) == 0 {
+		fmt.Println("No variables found.")
+		return nil
+	}
+	if opts.Format == "text" {
+		fmt.Println("Variables:")
+		for _, v := range variables {
+			fmt.Printf("  %s=%s\n", v.Key, displayValue(v.Value, opts.Reveal))
+		}
+		return nil
+	}
+	return printTable(variables, columns, opts, time.Now())
+}
+
+// listColumns validates the requested columns or returns the format's
+// default columns
+func listColumns(opts VarListOptions) ([]string, error) {
+	if opts.Format == "text" {
+		if len(opts.Columns) > 0 {
+			return nil, fmt.Errorf("--columns does not apply to the text format; use --format table")
+		}
+		return nil, nil
+	}
+	if len(opts.Columns) == 0 {
+		if opts.Format == "table" {
+			return []string{"key", "value"}, nil
+		}
+		return ListColumns, nil
+	}
+	for _, c := range opts.Columns {
+		if !slices.Contains(ListColumns, c) {
+			return nil, fmt.Errorf("unknown column %q (expected some of %s)", c, strings.Join(ListColumns, ","))
+		}
+	}
+	return opts.Columns, nil
+}
+
+// selectVariables filters and sorts variables for listing
+func selectVariables(variables []variable, opts VarListOptions) ([]variable, error) {
+	if opts.Filter != "" && opts.Regex != "" {
+		return nil, fmt.Errorf("--filter and --regex cannot be combined")
+	}
+	var glob filter.Options
+	if opts.Filter != "" {
+		glob.Only = []string{opts.Filter}
+		if err := glob.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	var re *regexp.Regexp
+	if opts.Regex != "" {
+		var err error
+		if re, err = regexp.Compile(opts.Regex); err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+
+	selected := make([]variable, 0, len(variables))
+	for _, v := range variables {
+		if !glob.Selects(v.Key) {
+			continue
+		}
+		if re != nil && !re.MatchString(v.Key) {
+			continue
+		}
+		selected = append(selected, v)
+	}
+
+	switch opts.Sort {
+	case "", "key":
+		sort.Slice(selected, func(i, j int) bool {
+			return selected[i].Key < selected[j].Key
+		})
+	case "updated":
+		sort.SliceStable(selected, func(i, j int) bool {
+			ti, tj := parseTime(selected[i].UpdatedAt), parseTime(selected[j].UpdatedAt)
+			if !ti.Equal(tj) {
+				return ti.After(tj)
+			}
+			return selected[i].Key < selected[j].Key
+		})
+	default:
+		return nil, fmt.Errorf("unknown sort order %q (expected key or updated)", opts.Sort)
+	}
+	return selected, nil
+}
+
+// columnName returns the field name of a column in JSON, YAML and CSV
+func columnName(column string) string {
+	switch column {
+	case "created", "updated":
+		return column + "_at"
+	}
+	return column
+}
+
+// columnValue returns a column of a variable. The value is masked unless
+// reveal is set and timestamps are formatted by formatTime.
+func columnValue(v variable, column string, reveal bool, formatTime func(string) string) string {
+	switch column {
+	case "key":
+		return v.Key
+	case "value":
+		return displayValue(v.Value, reveal)
+	case "created":
+		return formatTime(v.CreatedAt)
+	case "updated":
+		return formatTime(v.UpdatedAt)
+	case "size":
+		return fmt.Sprint(len(v.Value))
+	}
+	return ""
+}
+
+// printStructured prints variables as a JSON array or YAML list of
+// objects with the selected fields. Keys are sorted and timestamps are
+// RFC 3339, so the output is stable for tools like jq.
+func printStructured(variables []variable, columns []string, opts VarListOptions) error {
+	rows := make([]map[string]interface{}, len(variables))
+	for i, v := range variables {
+		row := make(map[string]interface{}, len(columns))
+		for _, c := range columns {
+			if c == "size" {
+				row[columnName(c)] = len(v.Value)
+				continue
+			}
+			row[columnName(c)] = columnValue(v, c, opts.Reveal, absoluteTime(opts.LocalTime))
+		}
+		rows[i] = row
+	}
+
+	if opts.Format == "yaml" {
+		data, err := yaml.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printCSV prints variables as CSV with a header row
+func printCSV(variables []variable, columns []string, opts VarListOptions) error {
+	w := csv.NewWriter(os.Stdout)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = columnName(c)
+	}
+	w.Write(header)
+
+	for _, v := range variables {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = columnValue(v, c, opts.Reveal, absoluteTime(opts.LocalTime))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// printTable prints variables as aligned columns, with timestamps relative
+// to now unless opts.LocalTime is set
+func printTable(variables []variable, columns []string, opts VarListOptions, now time.Time) error {
+	formatTime := func(s string) string {
+		return relativeTime(s, now)
+	}
+	if opts.LocalTime {
+		formatTime = func(s string) string {
+			t := parseTime(s)
+			if t.IsZero() {
+				return s
+			}
+			return t.Local().Format("2006-01-02 15:04:05")
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, v := range variables {
+		fields := make([]string, len(columns))
+		for i, c := range columns {
+			// Tabs and newlines would break the alignment
+			fields[i] = strings.NewReplacer("\t", `\t`, "\n", `\n`).Replace(columnValue(v, c, opts.Reveal, formatTime))
+		}
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+	return w.Flush()
+}
+
+// absoluteTime formats timestamps as RFC 3339 in UTC, or local time if
+// local is set
+func absoluteTime(local bool) func(string) string {
+	return func(s string) string {
+		t := parseTime(s)
+		if t.IsZero() {
+			return s
+		}
+		if local {
+			return t.Local().Format(time.RFC3339)
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+}
+
+// parseTime parses a backend timestamp, returning the zero time if it is
+// missing or invalid
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// relativeTime describes a timestamp relative to now, e.g. "3h ago"
+func relativeTime(s string, now time.Time) string {
+	t := parseTime(s)
+	if t.IsZero() {
+		return s
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < 0:
+		return t.Local().Format("2006-01-02 15:04")
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	}
+	return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+}
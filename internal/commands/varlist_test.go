@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectVariables(t *testing.T) {
+	variables := []variable{
+		{Key: "DB_HOST", UpdatedAt: "2025-01-01T00:00:00Z"},
+		{Key: "API_KEY", UpdatedAt: "2025-03-01T00:00:00Z"},
+		{Key: "DB_PORT", UpdatedAt: "2025-02-01T00:00:00Z"},
+	}
+
+	tests := []struct {
+		opts VarListOptions
+		want []string
+	}{
+		{VarListOptions{}, []string{"API_KEY", "DB_HOST", "DB_PORT"}},
+		{VarListOptions{Sort: "updated"}, []string{"API_KEY", "DB_PORT", "DB_HOST"}},
+		{VarListOptions{Filter: "DB_*"}, []string{"DB_HOST", "DB_PORT"}},
+		{VarListOptions{Regex: "(HOST|KEY)$", Sort: "updated"}, []string{"API_KEY", "DB_HOST"}},
+	}
+	for _, tt := range tests {
+		got, err := selectVariables(append([]variable{}, variables...), tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		var keys []string
+		for _, v := range got {
+			keys = append(keys, v.Key)
+		}
+		if len(keys) != len(tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.opts, keys, tt.want)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != tt.want[i] {
+				t.Errorf("%+v: got %v, want %v", tt.opts, keys, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, opts := range []VarListOptions{{Sort: "size"}, {Regex: "("}, {Filter: "["}, {Filter: "A*", Regex: "A"}} {
+		if _, err := selectVariables(variables, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestColumnValueSizeOfMaskedValue(t *testing.T) {
+	v := variable{Key: "TOKEN", Value: "abc"}
+	if got := columnValue(v, "size", false, nil); got != "3" {
+		t.Errorf("size of a masked value: got %s", got)
+	}
+	if got := columnValue(v, "value", false, nil); got == "abc" {
+		t.Error("value not masked")
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{4 * 24 * time.Hour, "4d ago"},
+		{90 * 24 * time.Hour, "3mo ago"},
+		{800 * 24 * time.Hour, "2y ago"},
+	}
+	for _, tt := range tests {
+		s := now.Add(-tt.ago).Format(time.RFC3339)
+		if got := relativeTime(s, now); got != tt.want {
+			t.Errorf("%v ago: got %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+
+	if got := relativeTime("yesterday", now); got != "yesterday" {
+		t.Errorf("unparseable timestamps should be kept, got %q", got)
+	}
+}
@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"not-env-cli/internal/client"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/dotenv"
+)
+
+// TransferOptions controls 'var rename', 'var copy' and 'var move'
+type TransferOptions struct {
+	// ToEnv is the target environment (see ParseEnvRef); empty means the
+	// current one
+	ToEnv string
+	// Delete removes each source key once its copy is verified
+	Delete bool
+	// Force overwrites target keys that exist with a different value
+	Force bool
+	// Confirm is the name of a protected target, confirming without a
+	// prompt
+	Confirm string
+}
+
+// keyRename is one key to copy and the name it gets in the target
+type keyRename struct {
+	Old, New string
+}
+
+// VarTransfer copies the variables matching from into the current or
+// another environment under the name to, deleting the originals if
+// opts.Delete is set. from may contain one * and to then needs one too,
+// e.g. LEGACY_* -> APP_*. Existing target keys are not overwritten without
+// opts.Force, and each copy is read back before its original is deleted.
+func VarTransfer(from, to string, opts TransferOptions) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if opts.Delete && cfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("deleting variables requires an ENV_ADMIN key")
+	}
+	source := client.NewClient(cfg.URL, cfg.APIKey)
+	target, targetCfg := source, cfg
+
+	var targetRef EnvRef
+	if opts.ToEnv != "" {
+		if targetRef, err = ParseEnvRef(opts.ToEnv); err != nil {
+			return err
+		}
+		if targetRef.File != "" {
+			return fmt.Errorf("cannot copy into a file (%s); use 'env export' instead", targetRef.File)
+		}
+		if target, targetCfg, err = targetRef.client(); err != nil {
+			return err
+		}
+		if sameEnvironment(cfg, targetCfg) {
+			return fmt.Errorf("%s is the current environment; use 'var rename' or leave out --to-env", targetRef)
+		}
+	} else if from == to {
+		return fmt.Errorf("source and target are the same; use --to-env to copy into another environment")
+	}
+	if targetCfg.KeyType != "ENV_ADMIN" {
+		return fmt.Errorf("writing variables requires an ENV_ADMIN key for the target")
+	}
+
+	sourceVars, err := fetchVariables(source)
+	if err != nil {
+		return err
+	}
+	values := variableMap(sourceVars)
+
+	renames, err := renameKeys(values, from, to, opts.ToEnv == "")
+	if err != nil {
+		return err
+	}
+
+	existing := values
+	if opts.ToEnv != "" {
+		targetVars, err := fetchVariables(target)
+		if err != nil {
+			return fmt.Errorf("failed to fetch variables of %s: %w", targetRef, err)
+		}
+		existing = variableMap(targetVars)
+	} else if err := checkOverlap(renames); err != nil {
+		return err
+	}
+
+	var clobbered []string
+	for _, r := range renames {
+		if value, ok := existing[r.New]; ok && value != values[r.Old] {
+			clobbered = append(clobbered, r.New)
+		}
+	}
+	if len(clobbered) > 0 && !opts.Force {
+		return fmt.Errorf("target keys already exist with other values: %s (use --force to overwrite)", strings.Join(clobbered, ", "))
+	}
+
+	if opts.ToEnv != "" && targetCfg.Protected {
+		ok, err := confirmPromotion(targetRef, targetCfg, PromoteOptions{Confirm: opts.Confirm})
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, r := range renames {
+		if err := transferKey(source, target, r, values[r.Old], opts.Delete); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", r.Old, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  %s -> %s\n", r.Old, r.New)
+	}
+
+	verb := "Copied"
+	switch {
+	case opts.Delete && opts.ToEnv == "":
+		verb = "Renamed"
+	case opts.Delete:
+		verb = "Moved"
+	}
+	done := len(renames) - failed
+	if opts.ToEnv != "" {
+		fmt.Printf("%s %d variables to %s\n", verb, done, targetRef)
+	} else {
+		fmt.Printf("%s %d variables\n", verb, done)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d variables failed; their originals were kept", failed, len(renames))
+	}
+	return nil
+}
+
+// sameEnvironment reports whether two sets of credentials belong to the
+// same environment of the same backend
+func sameEnvironment(a, b *config.Config) bool {
+	return a.URL == b.URL && a.EnvIDFromKey != nil && b.EnvIDFromKey != nil &&
+		*a.EnvIDFromKey == *b.EnvIDFromKey
+}
+
+// transferKey writes one value to the target, reads it back and only then
+// deletes the original if del is set
+func transferKey(source, target *client.Client, r keyRename, value string, del bool) error {
+	if err := setVariable(target, r.New, value); err != nil {
+		return fmt.Errorf("failed to write %s: %w", r.New, err)
+	}
+	written, err := fetchVariable(target, r.New)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", r.New, err)
+	}
+	if written != value {
+		return fmt.Errorf("%s was not stored correctly", r.New)
+	}
+	if del {
+		if err := deleteVariable(source, r.Old); err != nil {
+			return fmt.Errorf("copied to %s but failed to delete the original: %w", r.New, err)
+		}
+	}
+	return nil
+}
+
+// fetchVariable returns the value of a single variable
+func fetchVariable(cl *client.Client, key string) (string, error) {
+	resp, err := cl.Get(fmt.Sprintf("/variables/%s", key))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", client.ParseResponse(resp, nil)
+	}
+
+	var v variable
+	if err := client.ParseResponse(resp, &v); err != nil {
+		return "", err
+	}
+	return v.Value, nil
+}
+
+// renameKeys maps the keys matching from to their new names. A * in from
+// matches any text, which replaces the * in to. Within one environment
+// (sameEnv), keys that keep their name are left out: copying them onto
+// themselves and deleting the original would lose them.
+func renameKeys(values map[string]string, from, to string, sameEnv bool) ([]keyRename, error) {
+	if strings.ContainsAny(from, "?[") || strings.ContainsAny(to, "?[") {
+		return nil, fmt.Errorf("only * is supported in patterns")
+	}
+
+	stars := strings.Count(from, "*")
+	if stars == 0 {
+		if strings.Contains(to, "*") {
+			return nil, fmt.Errorf("%s has a * but %s does not", to, from)
+		}
+		if _, ok := values[from]; !ok {
+			return nil, fmt.Errorf("variable %s not found", from)
+		}
+		return checkNewKeys([]keyRename{{Old: from, New: to}})
+	}
+	if stars > 1 || strings.Count(to, "*") != 1 {
+		return nil, fmt.Errorf("patterns need exactly one * each, e.g. LEGACY_* and APP_*")
+	}
+
+	prefix, suffix, _ := strings.Cut(from, "*")
+	newPrefix, newSuffix, _ := strings.Cut(to, "*")
+
+	var renames []keyRename
+	matched := 0
+	for key := range values {
+		if len(key) < len(prefix)+len(suffix) || !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+		middle := key[len(prefix) : len(key)-len(suffix)]
+		matched++
+		if r := (keyRename{Old: key, New: newPrefix + middle + newSuffix}); !sameEnv || r.New != r.Old {
+			renames = append(renames, r)
+		}
+	}
+	if matched == 0 {
+		return nil, fmt.Errorf("no variables match %s", from)
+	}
+	if len(renames) == 0 {
+		return nil, fmt.Errorf("the variables matching %s already have their new names", from)
+	}
+	sort.Slice(renames, func(i, j int) bool {
+		return renames[i].Old < renames[j].Old
+	})
+	return checkNewKeys(renames)
+}
+
+// checkNewKeys refuses renames whose new names are not valid keys, before
+// anything is written
+func checkNewKeys(renames []keyRename) ([]keyRename, error) {
+	var bad []string
+	for _, r := range renames {
+		if !dotenv.ValidKey(r.New) {
+			bad = append(bad, fmt.Sprintf("  %q (from %s)", r.New, r.Old))
+		}
+	}
+	if len(bad) > 0 {
+		return nil, invalidKeysError("the new names", bad)
+	}
+	return renames, nil
+}
+
+// checkOverlap refuses renames within one environment whose new names are
+// also keys being renamed, since the order of the writes would decide the
+// result
+func checkOverlap(renames []keyRename) error {
+	old := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		old[r.Old] = true
+	}
+	for _, r := range renames {
+		if old[r.New] {
+			return fmt.Errorf("%s is both renamed and a new name; rename in two steps", r.New)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	values := map[string]string{
+		"LEGACY_HOST": "h",
+		"LEGACY_PORT": "p",
+		"OTHER":       "o",
+		"DB_URL_RO":   "u",
+		"X":           "x",
+		"XX":          "xx",
+	}
+
+	tests := []struct {
+		from, to string
+		sameEnv  bool
+		want     []keyRename
+	}{
+		{"OTHER", "NEW", true, []keyRename{{"OTHER", "NEW"}}},
+		{"LEGACY_*", "APP_*", true, []keyRename{{"LEGACY_HOST", "APP_HOST"}, {"LEGACY_PORT", "APP_PORT"}}},
+		{"*_RO", "*_READONLY", true, []keyRename{{"DB_URL_RO", "DB_URL_READONLY"}}},
+		{"LEGACY_*", "LEGACY_*", false, []keyRename{{"LEGACY_HOST", "LEGACY_HOST"}, {"LEGACY_PORT", "LEGACY_PORT"}}},
+		// X and XX keep their names and would be deleted after copying
+		{"X*", "*X", true, nil},
+		{"X*", "*X", false, []keyRename{{"X", "X"}, {"XX", "XX"}}},
+	}
+	for _, tt := range tests {
+		got, err := renameKeys(values, tt.from, tt.to, tt.sameEnv)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s -> %s: expected an error for identity renames, got %v", tt.from, tt.to, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s -> %s: %v", tt.from, tt.to, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s -> %s: got %v, want %v", tt.from, tt.to, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s -> %s: got %v, want %v", tt.from, tt.to, got, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, bad := range [][2]string{
+		{"MISSING", "X"},
+		{"NOPE_*", "X_*"},
+		{"LEGACY_*", "APP"},
+		{"OTHER", "X_*"},
+		{"*_*", "*_*"},
+		{"LEGACY_?", "APP_?"},
+		// New names must be valid keys
+		{"OTHER", "foo bar"},
+		{"LEGACY_*", "app-*"},
+		{"OTHER", "x/y"},
+	} {
+		if _, err := renameKeys(values, bad[0], bad[1], true); err == nil {
+			t.Errorf("%s -> %s: expected an error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestCheckOverlap(t *testing.T) {
+	if err := checkOverlap([]keyRename{{"A", "B"}, {"C", "D"}}); err != nil {
+		t.Error(err)
+	}
+	if err := checkOverlap([]keyRename{{"A_1", "A_B_1"}, {"A_B_1", "A_B_B_1"}}); err == nil {
+		t.Error("expected an error for chained renames")
+	}
+}
@@ -10,15 +10,35 @@ import (
 
 // Config represents the CLI configuration
 type Config struct {
-	URL          string `toml:"url"`
+	URL          string             `toml:"url"`
+	APIKey       string             `toml:"api_key"`
+	EnvID        *int64             `toml:"env_id,omitempty"`
+	KeyType      string             `toml:"key_type"`
+	EnvIDFromKey *int64             `toml:"env_id_from_key"`
+	Protected    bool               `toml:"protected,omitempty"`
+	Reveal       bool               `toml:"reveal,omitempty"`
+	Profiles     map[string]Profile `toml:"profiles,omitempty"`
+}
+
+// Profile holds a named set of credentials, typically one per environment.
+// An empty URL falls back to the top-level backend URL. Protected
+// environments require typing their name to confirm promotions into them.
+// Reveal shows values on the terminal instead of masking them.
+type Profile struct {
+	URL          string `toml:"url,omitempty"`
 	APIKey       string `toml:"api_key"`
-	EnvID        *int64 `toml:"env_id,omitempty"`
 	KeyType      string `toml:"key_type"`
-	EnvIDFromKey *int64 `toml:"env_id_from_key"`
+	EnvIDFromKey *int64 `toml:"env_id_from_key,omitempty"`
+	Protected    bool   `toml:"protected,omitempty"`
+	Reveal       bool   `toml:"reveal,omitempty"`
 }
 
 var configPath string
 
+// activeProfile is the profile Load resolves credentials from ("" for the
+// top-level credentials)
+var activeProfile string
+
 func init() {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -27,8 +47,33 @@ func init() {
 	configPath = filepath.Join(homeDir, ".not-env", "config")
 }
 
-// Load loads the configuration from disk
+// SetProfile selects the profile used by Load
+func SetProfile(name string) {
+	activeProfile = name
+}
+
+// ActiveProfile returns the profile selected with SetProfile
+func ActiveProfile() string {
+	return activeProfile
+}
+
+// Load loads the configuration from disk and applies the active profile.
+// The result is meant for reading credentials; use LoadFile to modify and
+// save the configuration.
 func Load() (*Config, error) {
+	config, err := LoadFile()
+	if err != nil {
+		return nil, err
+	}
+	if activeProfile == "" {
+		return config, nil
+	}
+	return config.WithProfile(activeProfile)
+}
+
+// LoadFile loads the configuration from disk as stored, without applying
+// the active profile
+func LoadFile() (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -45,6 +90,47 @@ func Load() (*Config, error) {
 	return &config, nil
 }
 
+// WithProfile returns a copy of the configuration whose credentials are
+// taken from the named profile
+func (c *Config) WithProfile(name string) (*Config, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found. Run 'not-env login --profile %s' first", name, name)
+	}
+
+	resolved := *c
+	if profile.URL != "" {
+		resolved.URL = profile.URL
+	}
+	resolved.APIKey = profile.APIKey
+	resolved.KeyType = profile.KeyType
+	resolved.EnvIDFromKey = profile.EnvIDFromKey
+	resolved.EnvID = nil
+	resolved.Protected = profile.Protected
+	resolved.Reveal = profile.Reveal
+	return &resolved, nil
+}
+
+// SetProfileCredentials stores credentials under the named profile, or in
+// the top-level fields when name is empty. Settings such as Protected and
+// Reveal are kept.
+func (c *Config) SetProfileCredentials(name string, profile Profile) {
+	if name == "" {
+		c.URL = profile.URL
+		c.APIKey = profile.APIKey
+		c.KeyType = profile.KeyType
+		c.EnvIDFromKey = profile.EnvIDFromKey
+		c.EnvID = nil
+		return
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+	profile.Protected = c.Profiles[name].Protected
+	profile.Reveal = c.Profiles[name].Reveal
+	c.Profiles[name] = profile
+}
+
 // Save saves the configuration to disk
 func (c *Config) Save() error {
 	dir := filepath.Dir(configPath)
@@ -77,3 +163,17 @@ func GetConfigPath() string {
 	return configPath
 }
 
+// SetConfigPath makes the CLI read and write its configuration at path
+// instead of ~/.not-env/config, e.g. a temporary file in tests. It returns
+// the previous path so it can be restored.
+func SetConfigPath(path string) string {
+	previous := configPath
+	configPath = path
+	return previous
+}
+
+// GetConfigDir returns the directory holding the config file and other
+// CLI state
+func GetConfigDir() string {
+	return filepath.Dir(configPath)
+}
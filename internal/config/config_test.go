@@ -99,3 +99,72 @@ func TestConfigClear(t *testing.T) {
 	}
 }
 
+
+func TestConfigProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalPath := configPath
+	configPath = filepath.Join(tmpDir, "config")
+	defer func() {
+		configPath = originalPath
+		SetProfile("")
+	}()
+
+	cfg := &Config{
+		URL:    "https://test.example.com",
+		APIKey: "app-key",
+	}
+	cfg.SetProfileCredentials("dev", Profile{APIKey: "dev-key", KeyType: "ENV_ADMIN"})
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	SetProfile("dev")
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if loaded.APIKey != "dev-key" || loaded.KeyType != "ENV_ADMIN" {
+		t.Errorf("profile credentials not applied: %+v", loaded)
+	}
+	if loaded.URL != cfg.URL {
+		t.Errorf("URL should fall back to top-level: got %q, want %q", loaded.URL, cfg.URL)
+	}
+
+	SetProfile("missing")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+
+	// Logging in again keeps profile settings
+	cfg.Profiles["dev"] = Profile{APIKey: "dev-key", Protected: true, Reveal: true}
+	cfg.SetProfileCredentials("dev", Profile{APIKey: "new-key"})
+	if p := cfg.Profiles["dev"]; p.APIKey != "new-key" || !p.Protected || !p.Reveal {
+		t.Errorf("profile settings not kept: %+v", p)
+	}
+}
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := FindProject(sub)
+	if err != nil || project != nil {
+		t.Fatalf("FindProject() without file = %v, %v; want nil, nil", project, err)
+	}
+
+	path := filepath.Join(root, ProjectFileName)
+	if err := os.WriteFile(path, []byte("profile = \"dev\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err = FindProject(sub)
+	if err != nil {
+		t.Fatalf("FindProject() error: %v", err)
+	}
+	if project == nil || project.Profile != "dev" || project.Dir() != root {
+		t.Errorf("FindProject() = %+v, want profile dev in %s", project, root)
+	}
+}
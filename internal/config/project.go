@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"not-env-cli/internal/filter"
+)
+
+// ProjectFileName is the name of the per-project configuration file
+const ProjectFileName = ".not-env.toml"
+
+// Project represents a per-project configuration file (.not-env.toml)
+type Project struct {
+	// Profile names the credentials (from ~/.not-env/config) used to load
+	// the project's environment. Empty means the top-level credentials.
+	Profile string `toml:"profile"`
+
+	// Load controls how variables are loaded for this project by the hook,
+	// 'env set' and 'run'. Command-line flags override these settings.
+	Load LoadSettings `toml:"load"`
+
+	// Import holds settings for 'env import'
+	Import ImportSettings `toml:"import"`
+
+	// Check holds settings for 'env check'
+	Check CheckSettings `toml:"check"`
+
+	// Path is the location of the project file
+	Path string `toml:"-"`
+}
+
+// CheckSettings lists the keys 'env check' expects when no file is given
+type CheckSettings struct {
+	// Required keys must exist with a non-empty value
+	Required []string `toml:"required"`
+}
+
+// ImportSettings holds a project's settings for importing variables
+type ImportSettings struct {
+	// EnvDeny lists glob patterns of process environment variables that
+	// 'env import --from-env' never imports, in addition to the built-in
+	// denylist
+	EnvDeny []string `toml:"env_deny"`
+}
+
+// LoadSettings holds a project's defaults for loading variables
+type LoadSettings struct {
+	filter.Options
+
+	// Expand resolves ${VAR} references between variables
+	Expand bool `toml:"expand"`
+}
+
+// Dir returns the directory containing the project file
+func (p *Project) Dir() string {
+	return filepath.Dir(p.Path)
+}
+
+// FindProject looks for a project file in dir and its parents.
+// It returns nil without an error when no project file is found.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return LoadProject(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject loads a project file from path
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var project Project
+	if err := toml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse project file %s: %w", path, err)
+	}
+	project.Path = path
+
+	return &project, nil
+}
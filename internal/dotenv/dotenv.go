@@ -0,0 +1,325 @@
+// Package dotenv parses .env files.
+//
+// Supported syntax:
+//   - KEY=VALUE, with optional whitespace around = and an optional
+//     leading "export "
+//   - comment lines starting with #, and inline comments after unquoted
+//     values when preceded by whitespace (KEY=value # comment)
+//   - single-quoted values, taken literally and possibly spanning lines
+//   - double-quoted values, possibly spanning lines, with the escapes
+//     \n, \r, \t, \", \\ and \$
+//
+// Values may reference other variables with ${VAR} (see package expand);
+// references are only resolved by Expand.
+package dotenv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"not-env-cli/internal/expand"
+)
+
+// Entry is a single KEY=VALUE assignment
+type Entry struct {
+	Key   string
+	Value string
+	// Line is the line number the assignment starts on
+	Line int
+
+	// template is the value in the syntax of package expand, with literal
+	// dollar signs escaped
+	template string
+}
+
+// SyntaxError describes a malformed line
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Errors is the list of syntax errors found in a file
+type Errors []*SyntaxError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Duplicate reports a key assigned more than once
+type Duplicate struct {
+	Key string
+	// Line is the line of the later assignment, which wins
+	Line int
+	// PreviousLine is the line of the earlier assignment
+	PreviousLine int
+}
+
+func (d Duplicate) String() string {
+	return fmt.Sprintf("line %d: duplicate key %s (previously set on line %d)", d.Line, d.Key, d.PreviousLine)
+}
+
+// Parse parses dotenv data and returns its assignments in file order,
+// including repeated keys. All malformed lines are reported together as
+// Errors.
+func Parse(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lines: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")}
+	entries := p.parse()
+	if len(p.errs) > 0 {
+		return entries, p.errs
+	}
+	return entries, nil
+}
+
+// Duplicates returns the keys of entries that are assigned more than once
+func Duplicates(entries []Entry) []Duplicate {
+	var duplicates []Duplicate
+	seen := make(map[string]int)
+	for _, e := range entries {
+		if line, ok := seen[e.Key]; ok {
+			duplicates = append(duplicates, Duplicate{Key: e.Key, Line: e.Line, PreviousLine: line})
+		}
+		seen[e.Key] = e.Line
+	}
+	return duplicates
+}
+
+// Dedupe collapses repeated keys, keeping the position of the first
+// assignment and the value of the last
+func Dedupe(entries []Entry) []Entry {
+	var result []Entry
+	index := make(map[string]int)
+	for _, e := range entries {
+		if i, ok := index[e.Key]; ok {
+			line := result[i].Line
+			result[i] = e
+			result[i].Line = line
+			continue
+		}
+		index[e.Key] = len(result)
+		result = append(result, e)
+	}
+	return result
+}
+
+// Expand resolves ${VAR} references between the entries, which must not
+// contain repeated keys. References to keys not in the file are looked up
+// in environ; pass nil to make them errors.
+func Expand(entries []Entry, environ map[string]string) ([]Entry, error) {
+	templates := make(map[string]string, len(entries))
+	for _, e := range entries {
+		templates[e.Key] = e.template
+	}
+
+	expander := expand.New(templates, environ)
+	expanded := make([]Entry, len(entries))
+	for i, e := range entries {
+		value, err := expander.Get(e.Key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", e.Line, err)
+		}
+		e.Value = value
+		e.template = strings.ReplaceAll(value, "$", "$$")
+		expanded[i] = e
+	}
+	return expanded, nil
+}
+
+type parser struct {
+	lines []string
+	// next is the index of the next line to read
+	next int
+	errs Errors
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) {
+	p.errs = append(p.errs, &SyntaxError{Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) parse() []Entry {
+	var entries []Entry
+	for p.next < len(p.lines) {
+		lineNo := p.next + 1
+		line := strings.TrimSpace(p.lines[p.next])
+		p.next++
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "export "); ok {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			p.errorf(lineNo, "expected KEY=VALUE")
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if !ValidKey(key) {
+			p.errorf(lineNo, "invalid key %q", key)
+			continue
+		}
+
+		value, template, ok := p.value(strings.TrimLeft(rest, " \t"), lineNo)
+		if !ok {
+			continue
+		}
+		entries = append(entries, Entry{Key: key, Value: value, Line: lineNo, template: template})
+	}
+	return entries
+}
+
+// value parses the value part of an assignment starting on line lineNo,
+// consuming further lines for multi-line quoted values
+func (p *parser) value(s string, lineNo int) (string, string, bool) {
+	if s == "" {
+		return "", "", true
+	}
+
+	switch s[0] {
+	case '\'':
+		raw, rest, ok := p.quoted(s[1:], '\'')
+		if !ok {
+			p.errorf(lineNo, "unterminated single-quoted value")
+			return "", "", false
+		}
+		if !p.trailing(rest, lineNo) {
+			return "", "", false
+		}
+		return raw, strings.ReplaceAll(raw, "$", "$$"), true
+
+	case '"':
+		raw, rest, ok := p.quoted(s[1:], '"')
+		if !ok {
+			p.errorf(lineNo, "unterminated double-quoted value")
+			return "", "", false
+		}
+		if !p.trailing(rest, lineNo) {
+			return "", "", false
+		}
+		value, template := unescape(raw)
+		return value, template, true
+	}
+
+	// Unquoted: an inline comment starts at whitespace followed by #
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	value := strings.TrimSpace(s)
+	return value, value, true
+}
+
+// quoted returns the text up to the closing quote, reading further lines
+// if needed, and the remainder of the line after the quote
+func (p *parser) quoted(s string, quote byte) (string, string, bool) {
+	var b strings.Builder
+	for {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\\' && quote == '"' && i+1 < len(s) {
+				b.WriteString(s[i : i+2])
+				i++
+				continue
+			}
+			if s[i] == quote {
+				return b.String(), s[i+1:], true
+			}
+			b.WriteByte(s[i])
+		}
+
+		if p.next >= len(p.lines) {
+			return "", "", false
+		}
+		b.WriteByte('\n')
+		s = p.lines[p.next]
+		p.next++
+	}
+}
+
+// trailing checks that only whitespace or a comment follows a closing quote
+func (p *parser) trailing(rest string, lineNo int) bool {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		p.errorf(lineNo, "unexpected characters after closing quote: %q", rest)
+		return false
+	}
+	return true
+}
+
+// unescape processes the escapes of a double-quoted value and returns the
+// value and its expansion template
+func unescape(raw string) (string, string) {
+	var value, template strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' || i+1 >= len(raw) {
+			value.WriteByte(c)
+			template.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch raw[i] {
+		case 'n':
+			value.WriteByte('\n')
+			template.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+			template.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+			template.WriteByte('\t')
+		case '"', '\\':
+			value.WriteByte(raw[i])
+			template.WriteByte(raw[i])
+		case '$':
+			value.WriteByte('$')
+			template.WriteString("$$")
+		default:
+			// Unknown escapes are kept as written
+			value.WriteByte('\\')
+			value.WriteByte(raw[i])
+			template.WriteByte('\\')
+			template.WriteByte(raw[i])
+		}
+	}
+	return value.String(), template.String()
+}
+
+// ValidKey reports whether key is a valid variable name: letters, digits
+// and underscores, not starting with a digit, so that shells accept it in
+// export statements
+func ValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package dotenv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `# comment
+export EXPORTED=yes
+PLAIN = value with spaces   # inline comment
+HASH=abc#def
+EMPTY=
+SINGLE='literal $HOME \n'
+DOUBLE="say \"hi\"\tthen\nnew line \$5"
+PEM="-----BEGIN KEY-----
+abc
+-----END KEY-----"
+MULTI='line one
+line two' # trailing comment
+`
+
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	want := []Entry{
+		{Key: "EXPORTED", Value: "yes", Line: 2},
+		{Key: "PLAIN", Value: "value with spaces", Line: 3},
+		{Key: "HASH", Value: "abc#def", Line: 4},
+		{Key: "EMPTY", Value: "", Line: 5},
+		{Key: "SINGLE", Value: `literal $HOME \n`, Line: 6},
+		{Key: "DOUBLE", Value: "say \"hi\"\tthen\nnew line $5", Line: 7},
+		{Key: "PEM", Value: "-----BEGIN KEY-----\nabc\n-----END KEY-----", Line: 8},
+		{Key: "MULTI", Value: "line one\nline two", Line: 11},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("Parse() returned %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		got := entries[i]
+		if got.Key != w.Key || got.Value != w.Value || got.Line != w.Line {
+			t.Errorf("entry %d = {%q, %q, line %d}, want {%q, %q, line %d}",
+				i, got.Key, got.Value, got.Line, w.Key, w.Value, w.Line)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	input := `GOOD=1
+not an assignment
+1BAD=x
+QUOTED="abc" trailing
+A.B=dotted
+A-B=dashed
+OPEN="never closed
+`
+
+	_, err := Parse(strings.NewReader(input))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Parse() error = %v, want Errors", err)
+	}
+
+	wantLines := []int{2, 3, 4, 5, 6, 7}
+	if len(errs) != len(wantLines) {
+		t.Fatalf("Parse() reported %d errors, want %d: %v", len(errs), len(wantLines), err)
+	}
+	for i, line := range wantLines {
+		if errs[i].Line != line {
+			t.Errorf("error %d on line %d, want line %d (%v)", i, errs[i].Line, line, errs[i])
+		}
+	}
+}
+
+func TestDuplicatesAndDedupe(t *testing.T) {
+	entries, err := Parse(strings.NewReader("A=1\nB=2\nA=3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicates := Duplicates(entries)
+	if len(duplicates) != 1 || duplicates[0].Key != "A" || duplicates[0].Line != 3 || duplicates[0].PreviousLine != 1 {
+		t.Errorf("Duplicates() = %+v", duplicates)
+	}
+
+	deduped := Dedupe(entries)
+	if len(deduped) != 2 || deduped[0].Key != "A" || deduped[0].Value != "3" || deduped[1].Key != "B" {
+		t.Errorf("Dedupe() = %+v", deduped)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	input := `HOST=db
+URL="postgres://${HOST}/app"
+LITERAL='${HOST}'
+ESCAPED="\${HOST}"
+`
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded, err := Expand(entries, nil)
+	if err != nil {
+		t.Fatalf("Expand() error: %v", err)
+	}
+
+	want := map[string]string{
+		"URL":     "postgres://db/app",
+		"LITERAL": "${HOST}",
+		"ESCAPED": "${HOST}",
+	}
+	for _, e := range expanded {
+		if w, ok := want[e.Key]; ok && e.Value != w {
+			t.Errorf("%s = %q, want %q", e.Key, e.Value, w)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package expand resolves ${VAR} references between variables.
+//
+// Supported forms:
+//   - ${VAR}          value of VAR
+//   - ${VAR:-default} value of VAR, or default if VAR is unset or empty
+//   - $$              a literal $
+//
+// A $ not followed by { or $ is kept as is, so values such as passwords
+// containing $ are not mangled.
+package expand
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expander resolves references within a set of variables
+type Expander struct {
+	vars map[string]string
+	// environ resolves references to keys outside vars; nil disallows them
+	environ map[string]string
+
+	resolved map[string]string
+	// stack holds the keys being resolved, to detect cycles
+	stack []string
+}
+
+// New returns an expander for vars. References to keys not in vars are
+// looked up in environ; pass nil to make them errors.
+func New(vars, environ map[string]string) *Expander {
+	return &Expander{
+		vars:     vars,
+		environ:  environ,
+		resolved: make(map[string]string, len(vars)),
+	}
+}
+
+// All returns every variable with its references resolved
+func (e *Expander) All() (map[string]string, error) {
+	result := make(map[string]string, len(e.vars))
+	for key := range e.vars {
+		value, err := e.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// Get returns the value of key with its references resolved
+func (e *Expander) Get(key string) (string, error) {
+	if value, ok := e.resolved[key]; ok {
+		return value, nil
+	}
+
+	raw, ok := e.vars[key]
+	if !ok {
+		return "", fmt.Errorf("variable %s not found", key)
+	}
+
+	for i, k := range e.stack {
+		if k == key {
+			cycle := append(append([]string{}, e.stack[i:]...), key)
+			return "", fmt.Errorf("reference cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	e.stack = append(e.stack, key)
+	value, err := e.expand(raw, key)
+	e.stack = e.stack[:len(e.stack)-1]
+	if err != nil {
+		return "", err
+	}
+
+	e.resolved[key] = value
+	return value, nil
+}
+
+// expand resolves the references in s, which is the value of (or a default
+// within) the variable owner
+func (e *Expander) expand(s, owner string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("%s: unterminated reference in %q", owner, s)
+			}
+			value, err := e.reference(s[i+2:end], owner)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// reference resolves the body of a ${...} reference
+func (e *Expander) reference(body, owner string) (string, error) {
+	name, def, hasDefault := strings.Cut(body, ":-")
+	if !validName(name) {
+		return "", fmt.Errorf("%s: invalid reference ${%s}", owner, body)
+	}
+
+	value, found, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if hasDefault && value == "" {
+		return e.expand(def, owner)
+	}
+	if !found {
+		return "", fmt.Errorf("%s: undefined variable %s", owner, name)
+	}
+	return value, nil
+}
+
+// lookup finds the resolved value of name in the variables or environ
+func (e *Expander) lookup(name string) (string, bool, error) {
+	if _, ok := e.vars[name]; ok {
+		value, err := e.Get(name)
+		return value, true, err
+	}
+	if e.environ != nil {
+		value, ok := e.environ[name]
+		return value, ok, nil
+	}
+	return "", false, nil
+}
+
+// closingBrace returns the index of the brace closing a reference whose
+// body starts at start, accounting for nested references in defaults
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// validName reports whether name is a valid variable name
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package expand
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	vars := map[string]string{
+		"DB_HOST":      "db.local",
+		"DB_PORT":      "5432",
+		"DB_USER":      "app",
+		"DATABASE_URL": "postgres://${DB_USER}@${DB_HOST}:${DB_PORT}/app",
+		"WITH_DEFAULT": "${MISSING:-${DB_HOST}}",
+		"ESCAPED":      "cost: $$5 and ${DB_PORT}",
+		"BARE_DOLLAR":  "pa$word",
+	}
+
+	got, err := New(vars, nil).All()
+	if err != nil {
+		t.Fatalf("All() error: %v", err)
+	}
+
+	want := map[string]string{
+		"DATABASE_URL": "postgres://app@db.local:5432/app",
+		"WITH_DEFAULT": "db.local",
+		"ESCAPED":      "cost: $5 and 5432",
+		"BARE_DOLLAR":  "pa$word",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestCycle(t *testing.T) {
+	vars := map[string]string{
+		"A": "${B}",
+		"B": "${C}",
+		"C": "${A}",
+	}
+
+	_, err := New(vars, nil).Get("A")
+	if err == nil || !strings.Contains(err.Error(), "A -> B -> C -> A") {
+		t.Errorf("Get() error = %v, want reference cycle", err)
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	vars := map[string]string{"URL": "http://${HOSTNAME}/"}
+
+	if _, err := New(vars, nil).Get("URL"); err == nil {
+		t.Error("Get() should fail when the process environment is not allowed")
+	}
+
+	got, err := New(vars, map[string]string{"HOSTNAME": "box"}).Get("URL")
+	if err != nil || got != "http://box/" {
+		t.Errorf("Get() = %q, %v; want %q", got, err, "http://box/")
+	}
+}
+
+func TestInvalidReference(t *testing.T) {
+	for _, value := range []string{"${", "${1A}", "${}"} {
+		if _, err := New(map[string]string{"K": value}, nil).Get("K"); err == nil {
+			t.Errorf("Get() should fail for %q", value)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package filter selects and renames variables before they are loaded,
+// so one environment can feed several components with the names each
+// expects.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Options selects variables by key and maps them to new names.
+// Selection (Only, Exclude) matches the original key. Renaming applies an
+// explicit Rename entry if there is one, otherwise StripPrefix followed by
+// AddPrefix.
+type Options struct {
+	// Only keeps keys matching at least one glob pattern (all keys if empty)
+	Only []string `toml:"only"`
+	// Exclude drops keys matching any glob pattern
+	Exclude []string `toml:"exclude"`
+	// StripPrefix removes a prefix from keys that have it
+	StripPrefix string `toml:"strip_prefix"`
+	// AddPrefix prepends a prefix to every key
+	AddPrefix string `toml:"add_prefix"`
+	// Rename maps original keys to new names
+	Rename map[string]string `toml:"rename"`
+}
+
+// IsZero reports whether the options keep every key unchanged
+func (o Options) IsZero() bool {
+	return len(o.Only) == 0 && len(o.Exclude) == 0 && o.StripPrefix == "" &&
+		o.AddPrefix == "" && len(o.Rename) == 0
+}
+
+// Merge returns o with the fields set in override replacing its own.
+// Rename entries are combined, with override winning on conflicts.
+func (o Options) Merge(override Options) Options {
+	merged := o
+	if len(override.Only) > 0 {
+		merged.Only = override.Only
+	}
+	if len(override.Exclude) > 0 {
+		merged.Exclude = override.Exclude
+	}
+	if override.StripPrefix != "" {
+		merged.StripPrefix = override.StripPrefix
+	}
+	if override.AddPrefix != "" {
+		merged.AddPrefix = override.AddPrefix
+	}
+	if len(override.Rename) > 0 {
+		merged.Rename = make(map[string]string, len(o.Rename)+len(override.Rename))
+		for oldKey, newKey := range o.Rename {
+			merged.Rename[oldKey] = newKey
+		}
+		for oldKey, newKey := range override.Rename {
+			merged.Rename[oldKey] = newKey
+		}
+	}
+	return merged
+}
+
+// Validate checks that all glob patterns are well-formed
+func (o Options) Validate() error {
+	for _, pattern := range append(append([]string{}, o.Only...), o.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Selects reports whether key passes the Only and Exclude patterns
+func (o Options) Selects(key string) bool {
+	if len(o.Only) > 0 && !MatchAny(o.Only, key) {
+		return false
+	}
+	return !MatchAny(o.Exclude, key)
+}
+
+// Name returns the name key is loaded under
+func (o Options) Name(key string) string {
+	if newKey, ok := o.Rename[key]; ok {
+		return newKey
+	}
+	return o.AddPrefix + strings.TrimPrefix(key, o.StripPrefix)
+}
+
+// Mapping pairs a selected key with the name it is loaded under
+type Mapping struct {
+	Key  string
+	Name string
+}
+
+// Apply selects and renames keys, given in load order. It fails if two
+// keys would be loaded under the same name.
+func (o Options) Apply(keys []string) ([]Mapping, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	var mapped []Mapping
+	sources := make(map[string]string)
+	for _, key := range keys {
+		if !o.Selects(key) {
+			continue
+		}
+		name := o.Name(key)
+		if name == "" {
+			return nil, fmt.Errorf("variable %s is renamed to an empty name", key)
+		}
+		if source, ok := sources[name]; ok {
+			return nil, fmt.Errorf("variables %s and %s would both be loaded as %s", source, key, name)
+		}
+		sources[name] = key
+		mapped = append(mapped, Mapping{Key: key, Name: name})
+	}
+	return mapped, nil
+}
+
+// MatchAny reports whether key matches any of the glob patterns
+func MatchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRename parses OLD=NEW rename mappings
+func ParseRename(mappings []string) (map[string]string, error) {
+	if len(mappings) == 0 {
+		return nil, nil
+	}
+	rename := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		oldKey, newKey, ok := strings.Cut(mapping, "=")
+		if !ok || oldKey == "" || newKey == "" {
+			return nil, fmt.Errorf("invalid rename %q (expected OLD=NEW)", mapping)
+		}
+		rename[oldKey] = newKey
+	}
+	return rename, nil
+}
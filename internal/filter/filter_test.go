@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	keys := []string{"APP_DB_URL", "APP_PORT", "APP_SECRET", "OTHER"}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []Mapping
+	}{
+		{
+			name: "no options",
+			opts: Options{},
+			want: []Mapping{
+				{Key: "APP_DB_URL", Name: "APP_DB_URL"},
+				{Key: "APP_PORT", Name: "APP_PORT"},
+				{Key: "APP_SECRET", Name: "APP_SECRET"},
+				{Key: "OTHER", Name: "OTHER"},
+			},
+		},
+		{
+			name: "only and exclude",
+			opts: Options{Only: []string{"APP_*"}, Exclude: []string{"*_SECRET"}},
+			want: []Mapping{
+				{Key: "APP_DB_URL", Name: "APP_DB_URL"},
+				{Key: "APP_PORT", Name: "APP_PORT"},
+			},
+		},
+		{
+			name: "prefixes and rename",
+			opts: Options{
+				Only:        []string{"APP_*"},
+				StripPrefix: "APP_",
+				AddPrefix:   "VITE_",
+				Rename:      map[string]string{"APP_PORT": "PORT"},
+			},
+			want: []Mapping{
+				{Key: "APP_DB_URL", Name: "VITE_DB_URL"},
+				{Key: "APP_PORT", Name: "PORT"},
+				{Key: "APP_SECRET", Name: "VITE_SECRET"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.Apply(keys)
+			if err != nil {
+				t.Fatalf("Apply() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyCollision(t *testing.T) {
+	opts := Options{StripPrefix: "APP_"}
+	if _, err := opts.Apply([]string{"APP_PORT", "PORT"}); err == nil {
+		t.Error("Apply() should fail when two keys map to the same name")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	project := Options{
+		Only:   []string{"APP_*"},
+		Rename: map[string]string{"A": "B", "C": "D"},
+	}
+	merged := project.Merge(Options{AddPrefix: "VITE_", Rename: map[string]string{"C": "E"}})
+
+	if !reflect.DeepEqual(merged.Only, []string{"APP_*"}) {
+		t.Errorf("Only = %v, want project value", merged.Only)
+	}
+	if merged.AddPrefix != "VITE_" {
+		t.Errorf("AddPrefix = %q, want VITE_", merged.AddPrefix)
+	}
+	if !reflect.DeepEqual(merged.Rename, map[string]string{"A": "B", "C": "E"}) {
+		t.Errorf("Rename = %v", merged.Rename)
+	}
+}
+
+func TestParseRename(t *testing.T) {
+	rename, err := ParseRename([]string{"OLD=NEW"})
+	if err != nil || rename["OLD"] != "NEW" {
+		t.Errorf("ParseRename() = %v, %v", rename, err)
+	}
+	if _, err := ParseRename([]string{"OLD"}); err == nil {
+		t.Error("ParseRename() should reject mappings without =")
+	}
+}
@@ -0,0 +1,98 @@
+// Package formats converts variables from and to configuration file
+// formats other than .env (which is handled by package dotenv).
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Import format names
+const (
+	Dotenv     = "dotenv"
+	JSON       = "json"
+	YAML       = "yaml"
+	TOML       = "toml"
+	Properties = "properties"
+	Compose    = "compose"
+	Kubernetes = "k8s"
+)
+
+// ImportFormats lists the formats Parse accepts, plus Dotenv
+var ImportFormats = []string{Dotenv, JSON, YAML, TOML, Properties, Compose, Kubernetes}
+
+// Var is a single key-value pair. Source is where Parse found it in the
+// input, e.g. db.hosts[0] or secret app data.TOKEN, for error messages.
+type Var struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// ParseOptions controls how nested data is turned into variables
+type ParseOptions struct {
+	// Separator joins the keys of nested objects and array indexes
+	// (default "_")
+	Separator string
+	// Service selects the service of a compose file
+	Service string
+}
+
+// Detect guesses the format of a file from its name and, for YAML files,
+// its content
+func Detect(path string, data []byte) string {
+	base := strings.ToLower(filepath.Base(path))
+	switch filepath.Ext(base) {
+	case ".json":
+		return JSON
+	case ".toml":
+		return TOML
+	case ".properties":
+		return Properties
+	case ".yaml", ".yml":
+		if strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose") {
+			return Compose
+		}
+		return detectYAML(data)
+	}
+	return Dotenv
+}
+
+// detectYAML tells Kubernetes manifests and compose files from plain YAML
+func detectYAML(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimRight(line, " \t\r")
+		switch {
+		case bytes.Equal(line, []byte("kind: Secret")), bytes.Equal(line, []byte("kind: ConfigMap")):
+			return Kubernetes
+		case bytes.Equal(line, []byte("services:")):
+			return Compose
+		}
+	}
+	return YAML
+}
+
+// Parse converts data in the given format into variables sorted by key
+func Parse(format string, data []byte, opts ParseOptions) ([]Var, error) {
+	if opts.Separator == "" {
+		opts.Separator = "_"
+	}
+
+	switch format {
+	case JSON:
+		return parseJSON(data, opts)
+	case YAML:
+		return parseYAML(data, opts)
+	case TOML:
+		return parseTOML(data, opts)
+	case Properties:
+		return parseProperties(data, opts)
+	case Compose:
+		return parseCompose(data, opts)
+	case Kubernetes:
+		return parseKubernetes(data)
+	}
+	return nil, fmt.Errorf("unsupported import format: %s (supported: %s)", format, strings.Join(ImportFormats, ", "))
+}
@@ -0,0 +1,297 @@
+package formats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want string
+	}{
+		{path: ".env", want: Dotenv},
+		{path: "config.json", want: JSON},
+		{path: "app.toml", want: TOML},
+		{path: "app.properties", want: Properties},
+		{path: "docker-compose.yml", want: Compose},
+		{path: "values.yaml", data: "replicas: 2\n", want: YAML},
+		{path: "secret.yaml", data: "apiVersion: v1\nkind: Secret\n", want: Kubernetes},
+		{path: "stack.yml", data: "services:\n  web: {}\n", want: Compose},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseNested(t *testing.T) {
+	want := []Var{
+		{Key: "db_host", Value: "localhost"},
+		{Key: "db_port", Value: "5432"},
+		{Key: "debug", Value: "true"},
+		{Key: "hosts_0", Value: "a"},
+		{Key: "hosts_1", Value: "b"},
+		{Key: "ratio", Value: "0.5"},
+	}
+
+	inputs := map[string]string{
+		JSON: `{"db": {"host": "localhost", "port": 5432}, "debug": true, "hosts": ["a", "b"], "ratio": 0.5}`,
+		YAML: "db:\n  host: localhost\n  port: 5432\ndebug: true\nhosts: [a, b]\nratio: 0.5\n",
+		TOML: "debug = true\nhosts = [\"a\", \"b\"]\nratio = 0.5\n[db]\nhost = \"localhost\"\nport = 5432\n",
+	}
+
+	for format, input := range inputs {
+		got, err := Parse(format, []byte(input), ParseOptions{})
+		if err != nil {
+			t.Errorf("Parse(%s) error: %v", format, err)
+			continue
+		}
+		if !reflect.DeepEqual(keyValues(got), want) {
+			t.Errorf("Parse(%s) = %v, want %v", format, got, want)
+		}
+	}
+}
+
+// keyValues drops the sources of vars, leaving the keys and values
+func keyValues(vars []Var) []Var {
+	stripped := make([]Var, len(vars))
+	for i, v := range vars {
+		stripped[i] = Var{Key: v.Key, Value: v.Value}
+	}
+	return stripped
+}
+
+func TestParseSources(t *testing.T) {
+	tests := []struct {
+		format, input string
+		opts          ParseOptions
+		want          map[string]string
+	}{
+		{JSON, `{"db": {"a b": 1}, "hosts": ["x"]}`, ParseOptions{}, map[string]string{"db_a b": "db.a b", "hosts_0": "hosts[0]"}},
+		{Properties, "db.host=x\n", ParseOptions{}, map[string]string{"db_host": "db.host"}},
+		{Compose, "services:\n  web:\n    environment:\n      - A=1\n", ParseOptions{}, map[string]string{"A": "services.web.environment[0]"}},
+		{Kubernetes, "kind: ConfigMap\nmetadata:\n  name: app\ndata:\n  a/b: x\n", ParseOptions{}, map[string]string{"a/b": "configmap app data.a/b"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.format, []byte(tt.input), tt.opts)
+		if err != nil {
+			t.Errorf("Parse(%s) error: %v", tt.format, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Parse(%s) = %v", tt.format, got)
+			continue
+		}
+		for _, v := range got {
+			if v.Source != tt.want[v.Key] {
+				t.Errorf("Parse(%s): source of %s = %q, want %q", tt.format, v.Key, v.Source, tt.want[v.Key])
+			}
+		}
+	}
+}
+
+func TestParseSeparator(t *testing.T) {
+	got, err := Parse(JSON, []byte(`{"db": {"host": "x"}}`), ParseOptions{Separator: "__"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Key != "db__host" {
+		t.Errorf("Parse() = %v, want db__host", got)
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	input := `# comment
+! also a comment
+db.host = localhost
+db.port:5432
+greeting hello world
+multi = one \
+        two
+escaped = tab\there é
+`
+	got, err := Parse(Properties, []byte(input), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Var{
+		{Key: "db_host", Value: "localhost"},
+		{Key: "db_port", Value: "5432"},
+		{Key: "escaped", Value: "tab\there é"},
+		{Key: "greeting", Value: "hello world"},
+		{Key: "multi", Value: "one two"},
+	}
+	if !reflect.DeepEqual(keyValues(got), want) {
+		t.Errorf("Parse(properties) = %v, want %v", got, want)
+	}
+}
+
+func TestParsePropertiesUnicodeEscapes(t *testing.T) {
+	tests := []struct{ input, want string }{
+		{`name=caf\u00e9`, "caf\u00e9"},
+		{`name=\u00e9`, "\u00e9"},
+		{`name=\ud83c\udf0d!`, "\U0001f30d!"},
+		{`name=\ud83c`, "\ufffd"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(Properties, []byte(tt.input), ParseOptions{})
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.input, err)
+			continue
+		}
+		if len(got) != 1 || got[0].Value != tt.want {
+			t.Errorf("Parse(%q) = %v, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{`name=\u00e`, `name=\u00zz`} {
+		if _, err := Parse(Properties, []byte(input), ParseOptions{}); err == nil {
+			t.Errorf("Parse(%q): expected an error", input)
+		}
+	}
+}
+
+func TestParseCompose(t *testing.T) {
+	input := `services:
+  web:
+    image: nginx
+    environment:
+      PORT: 8080
+      DEBUG: "false"
+      PASSTHROUGH:
+      EMPTY: ""
+  worker:
+    environment:
+      - QUEUE=jobs
+      - PASSTHROUGH
+`
+	if _, err := Parse(Compose, []byte(input), ParseOptions{}); err == nil {
+		t.Error("Parse(compose) should require --service with several services")
+	}
+
+	got, err := Parse(Compose, []byte(input), ParseOptions{Service: "worker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keyValues(got), []Var{{Key: "QUEUE", Value: "jobs"}}) {
+		t.Errorf("Parse(compose, worker) = %v", got)
+	}
+
+	got, err = Parse(Compose, []byte(input), ParseOptions{Service: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PASSTHROUGH has no value in either form and is skipped; an explicit
+	// empty string is kept
+	if !reflect.DeepEqual(keyValues(got), []Var{{Key: "DEBUG", Value: "false"}, {Key: "EMPTY", Value: ""}, {Key: "PORT", Value: "8080"}}) {
+		t.Errorf("Parse(compose, web) = %v", got)
+	}
+}
+
+func TestParseKubernetes(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  PASSWORD: czNjcmV0
+  TOKEN: b2xk
+stringData:
+  TOKEN: new
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: debug
+`
+	got, err := Parse(Kubernetes, []byte(input), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Var{
+		{Key: "LOG_LEVEL", Value: "debug"},
+		{Key: "PASSWORD", Value: "s3cret"},
+		{Key: "TOKEN", Value: "new"},
+	}
+	if !reflect.DeepEqual(keyValues(got), want) {
+		t.Errorf("Parse(k8s) = %v, want %v", got, want)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	vars := []Var{
+		{Key: "PLAIN", Value: "value"},
+		{Key: "QUOTED", Value: `say "hi" $HOME \ done`},
+		{Key: "PEM", Value: "-----BEGIN-----\nabc\n-----END-----"},
+		{Key: "UNICODE", Value: "héllo 🌍 # not a comment"},
+		{Key: "EMPTY", Value: ""},
+	}
+
+	// Write sorts by key
+	want := []Var{vars[4], vars[2], vars[0], vars[1], vars[3]}
+
+	for _, format := range []string{JSON, YAML, TOML, Properties} {
+		data, err := Write(format, vars, WriteOptions{})
+		if err != nil {
+			t.Errorf("Write(%s) error: %v", format, err)
+			continue
+		}
+		got, err := Parse(format, data, ParseOptions{})
+		if err != nil {
+			t.Errorf("Parse(%s) of written output error: %v\n%s", format, err, data)
+			continue
+		}
+		if !reflect.DeepEqual(keyValues(got), want) {
+			t.Errorf("%s round trip = %v, want %v\n%s", format, got, want, data)
+		}
+	}
+
+	data, err := Write(KubernetesSecret, vars, WriteOptions{Name: "app"})
+	if err != nil {
+		t.Fatalf("Write(k8s-secret) error: %v", err)
+	}
+	got, err := Parse(Kubernetes, data, ParseOptions{})
+	if err != nil || !reflect.DeepEqual(keyValues(got), want) {
+		t.Errorf("k8s-secret round trip = %v, %v; want %v\n%s", got, err, want, data)
+	}
+}
+
+func TestWriteLineFormats(t *testing.T) {
+	vars := []Var{
+		{Key: "B", Value: "50% ${x}"},
+		{Key: "A", Value: `a "b"`},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: Dotenv, want: "A=\"a \\\"b\\\"\"\nB=\"50% \\${x}\"\n"},
+		{format: DockerEnvFile, want: "A=a \"b\"\nB=50% ${x}\n"},
+		{format: Tfvars, want: "A = \"a \\\"b\\\"\"\nB = \"50% $${x}\"\n"},
+		{format: Systemd, want: "[Service]\nEnvironment=\"A=a \\\"b\\\"\"\nEnvironment=\"B=50%% ${x}\"\n"},
+	}
+
+	for _, tt := range tests {
+		data, err := Write(tt.format, vars, WriteOptions{})
+		if err != nil {
+			t.Errorf("Write(%s) error: %v", tt.format, err)
+			continue
+		}
+		if string(data) != tt.want {
+			t.Errorf("Write(%s) = %q, want %q", tt.format, data, tt.want)
+		}
+	}
+
+	if _, err := Write(DockerEnvFile, []Var{{Key: "K", Value: "a\nb"}}, WriteOptions{}); err == nil {
+		t.Error("Write(docker-env-file) should reject multi-line values")
+	}
+}
@@ -0,0 +1,430 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+func parseJSON(data []byte, opts ParseOptions) ([]Var, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return flattenDocument(doc, opts.Separator)
+}
+
+func parseYAML(data []byte, opts ParseOptions) ([]Var, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	return flattenDocument(doc, opts.Separator)
+}
+
+func parseTOML(data []byte, opts ParseOptions) ([]Var, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+	return flattenDocument(doc, opts.Separator)
+}
+
+// flattenDocument flattens a decoded document whose top level must be an
+// object
+func flattenDocument(doc interface{}, sep string) ([]Var, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	switch doc.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+	default:
+		return nil, fmt.Errorf("top level must be an object, got %T", doc)
+	}
+
+	values := make(map[string]Var)
+	if err := flatten("", "", doc, sep, values); err != nil {
+		return nil, err
+	}
+	return sortedVars(values), nil
+}
+
+// flatten adds the scalars of value to values, joining nested keys and
+// array indexes with sep. path is the location of value in the document,
+// e.g. db.hosts[0].
+func flatten(prefix, path string, value interface{}, sep string, values map[string]Var) error {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + sep + key
+	}
+	field := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if err := flatten(join(key), field(key), child, sep, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[interface{}]interface{}:
+		// YAML mappings with non-string keys
+		for key, child := range v {
+			name := fmt.Sprint(key)
+			if err := flatten(join(name), field(name), child, sep, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		for i, child := range v {
+			if err := flatten(join(strconv.Itoa(i)), fmt.Sprintf("%s[%d]", path, i), child, sep, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, exists := values[prefix]; exists {
+		return fmt.Errorf("key %s is defined more than once after flattening", prefix)
+	}
+	values[prefix] = Var{Key: prefix, Value: scalar(value), Source: path}
+	return nil
+}
+
+// scalar formats a decoded scalar value as a string
+func scalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(value)
+}
+
+// parseProperties parses a Java .properties file. Dots in keys denote
+// nesting and are replaced by the separator.
+func parseProperties(data []byte, opts ParseOptions) ([]Var, error) {
+	values := make(map[string]Var)
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// A trailing odd number of backslashes continues the line
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		rawKey, rawValue := splitProperty(line)
+		key, err := unescapeProperty(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		value, err := unescapeProperty(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		flat := strings.ReplaceAll(key, ".", opts.Separator)
+		values[flat] = Var{Key: flat, Value: value, Source: key}
+	}
+
+	return sortedVars(values), nil
+}
+
+// continued reports whether a properties line ends with an unescaped
+// backslash
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a properties line at the first unescaped =, : or
+// whitespace
+func splitProperty(line string) (string, string) {
+	const whitespace = " \t\f"
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case c == '=' || c == ':':
+			return line[:i], strings.TrimLeft(line[i+1:], whitespace)
+		case strings.IndexByte(whitespace, c) >= 0:
+			rest := strings.TrimLeft(line[i:], whitespace)
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], whitespace)
+			}
+			return line[:i], rest
+		}
+	}
+	return line, ""
+}
+
+// unescapeProperty processes properties escapes, including \uXXXX
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, n, err := unicodeEscape(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			// Characters outside the BMP are written as surrogate pairs
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1+n:], `\u`) {
+				if low, m, err := unicodeEscape(s[i+3+n:]); err == nil {
+					r = utf16.DecodeRune(r, low)
+					n += 2 + m
+				}
+			}
+			b.WriteRune(r)
+			i += n
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// unicodeEscape decodes the four hex digits of a \uXXXX escape at the
+// start of s
+func unicodeEscape(s string) (rune, int, error) {
+	if len(s) < 4 {
+		return 0, 0, fmt.Errorf("malformed \\u escape")
+	}
+	code, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed \\u escape: %q", s[:4])
+	}
+	return rune(code), 4, nil
+}
+
+// parseCompose reads the environment: block of a docker-compose service
+func parseCompose(data []byte, opts ParseOptions) ([]Var, error) {
+	var doc struct {
+		Services map[string]struct {
+			Environment yaml.Node `yaml:"environment"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+
+	var names []string
+	for name, service := range doc.Services {
+		if service.Environment.Kind != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	service := opts.Service
+	if service == "" {
+		switch len(names) {
+		case 0:
+			return nil, fmt.Errorf("no service in the compose file defines an environment")
+		case 1:
+			service = names[0]
+		default:
+			return nil, fmt.Errorf("several services define an environment (%s); choose one with --service", strings.Join(names, ", "))
+		}
+	}
+
+	svc, ok := doc.Services[service]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found in compose file", service)
+	}
+
+	values := make(map[string]Var)
+	path := "services." + service + ".environment"
+	node := svc.Environment
+	switch node.Kind {
+	case yaml.MappingNode:
+		var env map[string]interface{}
+		if err := node.Decode(&env); err != nil {
+			return nil, fmt.Errorf("invalid environment of service %s: %w", service, err)
+		}
+		for key, value := range env {
+			if value == nil {
+				// KEY: with no value passes the value through from the
+				// host, like KEY alone in the list form
+				continue
+			}
+			values[key] = Var{Key: key, Value: scalar(value), Source: path + "." + key}
+		}
+	case yaml.SequenceNode:
+		var env []string
+		if err := node.Decode(&env); err != nil {
+			return nil, fmt.Errorf("invalid environment of service %s: %w", service, err)
+		}
+		for i, entry := range env {
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				// KEY alone passes the value through from the host; there
+				// is nothing to import
+				continue
+			}
+			values[key] = Var{Key: key, Value: value, Source: fmt.Sprintf("%s[%d]", path, i)}
+		}
+	case 0:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("environment of service %s must be a mapping or a list", service)
+	}
+
+	return sortedVars(values), nil
+}
+
+// kubeObject is the subset of a Secret or ConfigMap (or a List of them)
+// that holds variables
+type kubeObject struct {
+	Kind       string            `yaml:"kind"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+	BinaryData map[string]string `yaml:"binaryData"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Items []kubeObject `yaml:"items"`
+}
+
+// parseKubernetes reads the data of every Secret and ConfigMap in a
+// (possibly multi-document) manifest, base64-decoding Secret data
+func parseKubernetes(data []byte) ([]Var, error) {
+	values := make(map[string]Var)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	found := false
+	for {
+		var obj kubeObject
+		err := decoder.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+
+		objects := []kubeObject{obj}
+		if obj.Kind == "List" {
+			objects = obj.Items
+		}
+		for _, o := range objects {
+			ok, err := kubeValues(o, values)
+			if err != nil {
+				return nil, err
+			}
+			found = found || ok
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no Secret or ConfigMap found in manifest")
+	}
+	return sortedVars(values), nil
+}
+
+// kubeValues adds the data of a Secret or ConfigMap to values and reports
+// whether obj was one
+func kubeValues(obj kubeObject, values map[string]Var) (bool, error) {
+	add := func(kind, field, key, value string) {
+		values[key] = Var{Key: key, Value: value, Source: fmt.Sprintf("%s %s %s.%s", kind, obj.Metadata.Name, field, key)}
+	}
+
+	switch obj.Kind {
+	case "Secret":
+		for key, encoded := range obj.Data {
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return false, fmt.Errorf("secret %s: invalid base64 in data.%s: %w", obj.Metadata.Name, key, err)
+			}
+			if !utf8.Valid(decoded) {
+				return false, fmt.Errorf("secret %s: data.%s is not valid UTF-8 text", obj.Metadata.Name, key)
+			}
+			add("secret", "data", key, string(decoded))
+		}
+		// stringData takes precedence over data, as in the API server
+		for key, value := range obj.StringData {
+			add("secret", "stringData", key, value)
+		}
+		return true, nil
+
+	case "ConfigMap":
+		for key, value := range obj.Data {
+			add("configmap", "data", key, value)
+		}
+		for key, encoded := range obj.BinaryData {
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return false, fmt.Errorf("configmap %s: invalid base64 in binaryData.%s: %w", obj.Metadata.Name, key, err)
+			}
+			add("configmap", "binaryData", key, string(decoded))
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// sortedVars returns values as variables sorted by key
+func sortedVars(values map[string]Var) []Var {
+	vars := make([]Var, 0, len(values))
+	for _, v := range values {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Key < vars[j].Key
+	})
+	return vars
+}
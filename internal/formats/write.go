@@ -0,0 +1,226 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Export format names (in addition to Dotenv, JSON, YAML, TOML and
+// Properties)
+const (
+	KubernetesSecret    = "k8s-secret"
+	KubernetesConfigMap = "k8s-configmap"
+	DockerEnvFile       = "docker-env-file"
+	Tfvars              = "tfvars"
+	Systemd             = "systemd"
+)
+
+// ExportFormats lists the formats Write accepts
+var ExportFormats = []string{Dotenv, JSON, YAML, TOML, KubernetesSecret, KubernetesConfigMap, DockerEnvFile, Tfvars, Properties, Systemd}
+
+// WriteOptions holds format-specific settings for Write
+type WriteOptions struct {
+	// Name is the metadata.name of Kubernetes manifests
+	Name string
+}
+
+// Write renders vars in the given format. Variables are sorted by key so
+// the output is deterministic and diff-friendly.
+func Write(format string, vars []Var, opts WriteOptions) ([]byte, error) {
+	sorted := make([]Var, len(vars))
+	copy(sorted, vars)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+
+	switch format {
+	case Dotenv:
+		return writeLines(sorted, func(v Var) (string, error) {
+			return v.Key + "=" + dotenvQuote(v.Value), nil
+		})
+	case JSON:
+		data, err := json.MarshalIndent(varMap(sorted), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case YAML:
+		return marshalYAML(varMap(sorted))
+	case TOML:
+		return toml.Marshal(varMap(sorted))
+	case KubernetesSecret, KubernetesConfigMap:
+		return writeKubernetes(format, sorted, opts)
+	case DockerEnvFile:
+		return writeLines(sorted, func(v Var) (string, error) {
+			if strings.ContainsAny(v.Value, "\r\n") {
+				return "", fmt.Errorf("%s contains a line break, which docker env files cannot represent", v.Key)
+			}
+			return v.Key + "=" + v.Value, nil
+		})
+	case Tfvars:
+		return writeLines(sorted, func(v Var) (string, error) {
+			return v.Key + " = " + hclQuote(v.Value), nil
+		})
+	case Properties:
+		return writeLines(sorted, func(v Var) (string, error) {
+			return propertiesEscape(v.Key, true) + "=" + propertiesEscape(v.Value, false), nil
+		})
+	case Systemd:
+		data, err := writeLines(sorted, func(v Var) (string, error) {
+			return "Environment=" + systemdQuote(v.Key+"="+v.Value), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte("[Service]\n"), data...), nil
+	}
+	return nil, fmt.Errorf("unsupported export format: %s (supported: %s)", format, strings.Join(ExportFormats, ", "))
+}
+
+// writeLines renders one line per variable
+func writeLines(vars []Var, line func(Var) (string, error)) ([]byte, error) {
+	var b bytes.Buffer
+	for _, v := range vars {
+		l, err := line(v)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func varMap(vars []Var) map[string]string {
+	m := make(map[string]string, len(vars))
+	for _, v := range vars {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+// writeKubernetes renders a Secret (base64 data) or ConfigMap manifest
+func writeKubernetes(format string, vars []Var, opts WriteOptions) ([]byte, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("a name is required for Kubernetes manifests")
+	}
+
+	type metadata struct {
+		Name string `yaml:"name"`
+	}
+	manifest := struct {
+		APIVersion string            `yaml:"apiVersion"`
+		Kind       string            `yaml:"kind"`
+		Metadata   metadata          `yaml:"metadata"`
+		Type       string            `yaml:"type,omitempty"`
+		Data       map[string]string `yaml:"data"`
+	}{
+		APIVersion: "v1",
+		Metadata:   metadata{Name: opts.Name},
+		Data:       make(map[string]string, len(vars)),
+	}
+
+	if format == KubernetesSecret {
+		manifest.Kind = "Secret"
+		manifest.Type = "Opaque"
+		for _, v := range vars {
+			manifest.Data[v.Key] = base64.StdEncoding.EncodeToString([]byte(v.Value))
+		}
+	} else {
+		manifest.Kind = "ConfigMap"
+		for _, v := range vars {
+			manifest.Data[v.Key] = v.Value
+		}
+	}
+
+	return marshalYAML(manifest)
+}
+
+// marshalYAML encodes v with the two-space indentation common in
+// Kubernetes manifests
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dotenvQuote quotes a value for a .env file when needed, using escapes
+// understood by package dotenv
+func dotenvQuote(value string) string {
+	safe := value != ""
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-.,/:@+=%", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return value
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// hclQuote quotes a value as an HCL string, escaping template sequences
+func hclQuote(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{")
+	return `"` + r.Replace(value) + `"`
+}
+
+// systemdQuote quotes an Environment= assignment using C-style escapes,
+// doubling % to avoid specifier expansion
+func systemdQuote(assignment string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "%", "%%")
+	return `"` + r.Replace(assignment) + `"`
+}
+
+// propertiesEscape escapes a .properties key or value, writing non-ASCII
+// characters as \uXXXX so the file is valid ISO-8859-1
+func propertiesEscape(s string, isKey bool) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\f':
+			b.WriteString(`\f`)
+		case c == '=' || c == ':':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c == ' ' && (isKey || i == 0):
+			b.WriteString(`\ `)
+		case (c == '#' || c == '!') && i == 0:
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c > 0x7e || c < 0x20:
+			for _, u := range utf16.Encode([]rune{c}) {
+				fmt.Fprintf(&b, `\u%04x`, u)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+// Package plan computes and prints the changes needed to bring an
+// environment's variables in line with a desired set of variables.
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action is what a plan does with a single key
+type Action string
+
+const (
+	// Add creates a key that does not exist yet
+	Add Action = "add"
+	// Change overwrites a key with a different value
+	Change Action = "change"
+	// Unchanged leaves a key that already has the desired value
+	Unchanged Action = "unchanged"
+	// Remove deletes a key that is not in the desired set
+	Remove Action = "remove"
+	// Keep leaves a key alone although it differs from or is missing in
+	// the desired set
+	Keep Action = "keep"
+)
+
+// Item is the planned action for one key. Old is the current value (empty
+// for additions), New the desired value (empty for removals). InSource
+// reports whether the key is in the desired set, since its desired value
+// may be empty.
+type Item struct {
+	Key      string
+	Action   Action
+	Old      string
+	New      string
+	InSource bool
+}
+
+// Conflict reports whether the key exists with a different value
+func (it Item) Conflict() bool {
+	return it.Action == Change || (it.Action == Keep && it.InSource && it.Old != it.New)
+}
+
+// Options controls how a plan treats conflicts and extra keys
+type Options struct {
+	// Prune removes current keys that are not in the desired set
+	Prune bool
+	// NoOverwrite keeps current values that differ from the desired ones
+	NoOverwrite bool
+}
+
+// Plan is the ordered list of actions for all keys involved
+type Plan struct {
+	Items []Item
+}
+
+// New compares the current variables with the desired ones. Items are
+// sorted by key.
+func New(current, desired map[string]string, opts Options) *Plan {
+	p := &Plan{}
+
+	for key, value := range desired {
+		old, exists := current[key]
+		switch {
+		case !exists:
+			p.Items = append(p.Items, Item{Key: key, Action: Add, New: value, InSource: true})
+		case old == value:
+			p.Items = append(p.Items, Item{Key: key, Action: Unchanged, Old: old, New: value, InSource: true})
+		case opts.NoOverwrite:
+			p.Items = append(p.Items, Item{Key: key, Action: Keep, Old: old, New: value, InSource: true})
+		default:
+			p.Items = append(p.Items, Item{Key: key, Action: Change, Old: old, New: value, InSource: true})
+		}
+	}
+
+	for key, old := range current {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if opts.Prune {
+			p.Items = append(p.Items, Item{Key: key, Action: Remove, Old: old})
+		} else {
+			p.Items = append(p.Items, Item{Key: key, Action: Keep, Old: old})
+		}
+	}
+
+	sort.Slice(p.Items, func(i, j int) bool {
+		return p.Items[i].Key < p.Items[j].Key
+	})
+	return p
+}
+
+// Count returns the number of items with the given action
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, it := range p.Items {
+		if it.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Empty reports whether applying the plan would not change anything
+func (p *Plan) Empty() bool {
+	return p.Count(Add)+p.Count(Change)+p.Count(Remove) == 0
+}
+
+// Hash returns a short fingerprint of a value, so changes can be shown
+// without revealing values
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// HashMinLength is the length from which Fingerprint shows a value's hash.
+// Hash is unsalted, so for shorter values it would let anyone who sees it
+// recover the value by brute force.
+const HashMinLength = 16
+
+// Fingerprint describes a value without revealing it: its Hash if it is at
+// least HashMinLength characters long, otherwise only its length
+func Fingerprint(value string) string {
+	n := utf8.RuneCountInString(value)
+	switch {
+	case n == 0:
+		return "(empty)"
+	case n < HashMinLength:
+		return fmt.Sprintf("(%d chars)", n)
+	}
+	return Hash(value)
+}
+
+// Print writes the plan, one key per line followed by a summary. Values are
+// shown as fingerprints unless showValues is set.
+func (p *Plan) Print(w io.Writer, showValues bool) {
+	show := Fingerprint
+	if showValues {
+		show = func(value string) string {
+			return fmt.Sprintf("%q", value)
+		}
+	}
+
+	width := 0
+	for _, it := range p.Items {
+		if len(it.Key) > width {
+			width = len(it.Key)
+		}
+	}
+
+	for _, it := range p.Items {
+		var mark, detail string
+		switch it.Action {
+		case Add:
+			mark, detail = "+", show(it.New)
+		case Change:
+			mark, detail = "~", show(it.Old)+" -> "+show(it.New)
+		case Unchanged:
+			mark, detail = " ", "(unchanged)"
+		case Remove:
+			mark, detail = "-", show(it.Old)
+		case Keep:
+			if it.Conflict() {
+				mark, detail = "!", show(it.Old)+" -> "+show(it.New)+" (kept)"
+			} else {
+				mark, detail = " ", "(not in source, kept)"
+			}
+		}
+		fmt.Fprintf(w, "  %s %-*s  %s\n", mark, width, it.Key, detail)
+	}
+
+	fmt.Fprintf(w, "Plan: %s\n", p.Summary())
+}
+
+// Summary describes the number of keys per action
+func (p *Plan) Summary() string {
+	parts := []string{
+		fmt.Sprintf("%d to add", p.Count(Add)),
+		fmt.Sprintf("%d to change", p.Count(Change)),
+		fmt.Sprintf("%d to remove", p.Count(Remove)),
+		fmt.Sprintf("%d unchanged", p.Count(Unchanged)),
+	}
+	if n := p.Count(Keep); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d kept", n))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,99 @@
+package plan
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	current := map[string]string{"SAME": "1", "DIFF": "old", "EXTRA": "x"}
+	desired := map[string]string{"SAME": "1", "DIFF": "new", "NEW": "n"}
+
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]Action
+	}{
+		{
+			name: "default",
+			want: map[string]Action{"DIFF": Change, "EXTRA": Keep, "NEW": Add, "SAME": Unchanged},
+		},
+		{
+			name: "prune",
+			opts: Options{Prune: true},
+			want: map[string]Action{"DIFF": Change, "EXTRA": Remove, "NEW": Add, "SAME": Unchanged},
+		},
+		{
+			name: "no overwrite",
+			opts: Options{NoOverwrite: true},
+			want: map[string]Action{"DIFF": Keep, "EXTRA": Keep, "NEW": Add, "SAME": Unchanged},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(current, desired, tt.opts)
+			if len(p.Items) != len(tt.want) {
+				t.Fatalf("got %d items, want %d", len(p.Items), len(tt.want))
+			}
+			for i, it := range p.Items {
+				if i > 0 && p.Items[i-1].Key >= it.Key {
+					t.Errorf("items not sorted: %s before %s", p.Items[i-1].Key, it.Key)
+				}
+				if tt.want[it.Key] != it.Action {
+					t.Errorf("%s: got %s, want %s", it.Key, it.Action, tt.want[it.Key])
+				}
+			}
+		})
+	}
+}
+
+func TestPrintMasksValues(t *testing.T) {
+	p := New(map[string]string{"A": "secret-old-long-value"}, map[string]string{"A": "secret-new-long-value"}, Options{})
+
+	var buf bytes.Buffer
+	p.Print(&buf, false)
+	out := buf.String()
+
+	if strings.Contains(out, "secret") {
+		t.Errorf("plan reveals values:\n%s", out)
+	}
+	if !strings.Contains(out, Hash("secret-old-long-value")+" -> "+Hash("secret-new-long-value")) {
+		t.Errorf("plan does not show change hashes:\n%s", out)
+	}
+	if !strings.Contains(out, "Plan: 0 to add, 1 to change, 0 to remove, 0 unchanged") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+}
+
+func TestEmptyDesiredValueConflicts(t *testing.T) {
+	p := New(map[string]string{"A": "set", "B": "x"}, map[string]string{"A": ""}, Options{NoOverwrite: true})
+
+	if !p.Items[0].Conflict() {
+		t.Error("an empty desired value should conflict with the current one")
+	}
+	if p.Items[1].Conflict() {
+		t.Error("a key missing from the source should not conflict")
+	}
+
+	var buf bytes.Buffer
+	p.Print(&buf, false)
+	out := buf.String()
+	if !strings.Contains(out, "! A") || strings.Count(out, "not in source") != 1 {
+		t.Errorf("unexpected plan:\n%s", out)
+	}
+}
+
+func TestFingerprintHidesShortValues(t *testing.T) {
+	if got := Fingerprint("hunter2"); got != "(7 chars)" || strings.Contains(got, Hash("hunter2")) {
+		t.Errorf("Fingerprint() of a short value = %q", got)
+	}
+	if got := Fingerprint(""); got != "(empty)" {
+		t.Errorf("Fingerprint() of an empty value = %q", got)
+	}
+	long := "correct horse battery staple"
+	if got := Fingerprint(long); got != Hash(long) {
+		t.Errorf("Fingerprint() of a long value = %q, want its hash", got)
+	}
+}
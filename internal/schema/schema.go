@@ -0,0 +1,224 @@
+// Package schema validates variable values against an optional schema file
+// declaring each key's type, whether it is required and its default.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// FileName is the name of the schema file, looked up like the project file
+const FileName = "not-env.schema.toml"
+
+// Types lists the supported value types
+var Types = []string{"string", "int", "bool", "url", "email", "duration", "enum", "regex", "json"}
+
+// Schema declares the variables of an environment
+type Schema struct {
+	Keys map[string]*Key `toml:"keys"`
+
+	// Path is the location of the schema file
+	Path string `toml:"-"`
+}
+
+// Key declares one variable. Type defaults to "string". Values lists the
+// allowed values of an enum and Pattern the regular expression a regex
+// value must match completely.
+type Key struct {
+	Type        string   `toml:"type"`
+	Required    bool     `toml:"required"`
+	Default     *string  `toml:"default"`
+	Description string   `toml:"description"`
+	Values      []string `toml:"values"`
+	Pattern     string   `toml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// Problem is a value that does not satisfy the schema
+type Problem struct {
+	Key     string
+	Message string
+}
+
+func (p Problem) Error() string {
+	return p.Key + ": " + p.Message
+}
+
+// Find looks for a schema file in dir and its parents. It returns nil
+// without an error when there is none.
+func Find(dir string) (*Schema, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return Load(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads and checks a schema file
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	s.Path = path
+	return s, nil
+}
+
+// Parse parses and checks a schema
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	decoder := toml.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	for _, name := range s.keyNames() {
+		k := s.Keys[name]
+		if k == nil {
+			// An empty table declares a plain string
+			k = &Key{}
+			s.Keys[name] = k
+		}
+		if k.Type == "" {
+			k.Type = "string"
+		}
+		if !slices.Contains(Types, k.Type) {
+			return nil, fmt.Errorf("%s: unknown type %q (expected one of %s)", name, k.Type, strings.Join(Types, ", "))
+		}
+		if k.Type == "enum" && len(k.Values) == 0 {
+			return nil, fmt.Errorf("%s: enum needs values", name)
+		}
+		if k.Type == "regex" {
+			if k.Pattern == "" {
+				return nil, fmt.Errorf("%s: regex needs a pattern", name)
+			}
+			re, err := regexp.Compile("^(?:" + k.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern: %w", name, err)
+			}
+			k.re = re
+		}
+		if k.Default != nil {
+			if err := k.Check(*k.Default); err != nil {
+				return nil, fmt.Errorf("%s: invalid default: %w", name, err)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// keyNames returns the declared keys in order
+func (s *Schema) keyNames() []string {
+	names := make([]string, 0, len(s.Keys))
+	for name := range s.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check reports whether value is valid for the key's type
+func (k *Key) Check(value string) error {
+	switch k.Type {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a boolean (use true or false)", value)
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%q is not an absolute URL", value)
+		}
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return fmt.Errorf("%q is not an email address", value)
+		}
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%q is not a duration (e.g. 30s, 5m, 1h)", value)
+		}
+	case "enum":
+		if !slices.Contains(k.Values, value) {
+			return fmt.Errorf("%q is not one of %s", value, strings.Join(k.Values, ", "))
+		}
+	case "regex":
+		if !k.re.MatchString(value) {
+			return fmt.Errorf("%q does not match %s", value, k.Pattern)
+		}
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("value is not valid JSON")
+		}
+	}
+	return nil
+}
+
+// CheckValue validates the value of a single key and returns the problem,
+// or nil if it is valid. Keys the schema does not declare are accepted.
+func (s *Schema) CheckValue(key, value string) *Problem {
+	k, ok := s.Keys[key]
+	if !ok {
+		return nil
+	}
+	if err := k.Check(value); err != nil {
+		return &Problem{Key: key, Message: err.Error()}
+	}
+	return nil
+}
+
+// Validate checks a complete set of variables: every value, and that
+// required keys without a default exist
+func (s *Schema) Validate(variables map[string]string) []Problem {
+	var problems []Problem
+	for _, name := range s.keyNames() {
+		k := s.Keys[name]
+		value, ok := variables[name]
+		if !ok {
+			if k.Required && k.Default == nil {
+				problems = append(problems, Problem{Key: name, Message: "required but missing"})
+			}
+			continue
+		}
+		if err := k.Check(value); err != nil {
+			problems = append(problems, Problem{Key: name, Message: err.Error()})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+[keys.PORT]
+type = "int"
+required = true
+description = "HTTP port"
+
+[keys.DEBUG]
+type = "bool"
+default = "false"
+
+[keys.API_URL]
+type = "url"
+
+[keys.ADMIN]
+type = "email"
+
+[keys.TIMEOUT]
+type = "duration"
+
+[keys.LOG_LEVEL]
+type = "enum"
+values = ["debug", "info", "warn"]
+
+[keys.REGION]
+type = "regex"
+pattern = "[a-z]+-[0-9]"
+
+[keys.FEATURES]
+type = "json"
+required = true
+default = "{}"
+
+[keys.NAME]
+`
+
+func TestCheckTypes(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value string
+		valid      bool
+	}{
+		{"PORT", "8080", true},
+		{"PORT", "80a", false},
+		{"DEBUG", "true", true},
+		{"DEBUG", "maybe", false},
+		{"API_URL", "https://api.example.com/v1", true},
+		{"API_URL", "api.example.com", false},
+		{"ADMIN", "ops@example.com", true},
+		{"ADMIN", "Ops <ops@example.com>", false},
+		{"TIMEOUT", "1m30s", true},
+		{"TIMEOUT", "90", false},
+		{"LOG_LEVEL", "info", true},
+		{"LOG_LEVEL", "trace", false},
+		{"REGION", "eu-1", true},
+		{"REGION", "eu-1x", false},
+		{"FEATURES", `{"beta": true}`, true},
+		{"FEATURES", `{beta}`, false},
+		{"NAME", "anything", true},
+		{"UNDECLARED", "anything", true},
+	}
+	for _, tt := range tests {
+		problem := s.CheckValue(tt.key, tt.value)
+		if (problem == nil) != tt.valid {
+			t.Errorf("%s=%q: valid=%v, problem %v", tt.key, tt.value, tt.valid, problem)
+		}
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// FEATURES is required but has a default
+	problems := s.Validate(map[string]string{"LOG_LEVEL": "trace"})
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.Key)
+	}
+	if strings.Join(got, ",") != "LOG_LEVEL,PORT" {
+		t.Errorf("got problems %v", problems)
+	}
+
+	if problems := s.Validate(map[string]string{"PORT": "443"}); len(problems) != 0 {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
+
+func TestParseRejectsInvalidSchema(t *testing.T) {
+	for _, data := range []string{
+		"[keys.A]\ntype = \"number\"\n",
+		"[keys.A]\ntype = \"enum\"\n",
+		"[keys.A]\ntype = \"regex\"\npattern = \"(\"\n",
+		"[keys.A]\ntype = \"int\"\ndefault = \"x\"\n",
+		"[keys.A]\nrequird = true\n",
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestFindWalksUp(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := Find(sub); err != nil || s != nil {
+		t.Fatalf("expected no schema, got %v, %v", s, err)
+	}
+
+	path := filepath.Join(root, FileName)
+	if err := os.WriteFile(path, []byte(testSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Find(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s == nil || s.Path != path {
+		t.Fatalf("expected schema at %s, got %+v", path, s)
+	}
+}
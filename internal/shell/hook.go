@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+const bashHook = `_not_env_hook() {
+  local previous_exit_status=$?
+  if [[ "${_NOT_ENV_PWD-}" != "$PWD" ]]; then
+    _NOT_ENV_PWD="$PWD"
+    eval "$(_NOT_ENV_STATE="${_NOT_ENV_STATE-}" %[1]s hook export bash)"
+  fi
+  return $previous_exit_status
+}
+not-env() {
+  _NOT_ENV_STATE="${_NOT_ENV_STATE-}" %[1]s "$@"
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_not_env_hook;"* ]]; then
+  PROMPT_COMMAND="_not_env_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`
+
+const zshHook = `_not_env_hook() {
+  eval "$(_NOT_ENV_STATE="${_NOT_ENV_STATE-}" %[1]s hook export zsh)"
+}
+not-env() {
+  _NOT_ENV_STATE="${_NOT_ENV_STATE-}" %[1]s "$@"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _not_env_hook
+_not_env_hook
+`
+
+const fishHook = `function __not_env_hook --on-variable PWD
+  _NOT_ENV_STATE=$_NOT_ENV_STATE %[1]s hook export fish | source
+end
+function not-env
+  _NOT_ENV_STATE=$_NOT_ENV_STATE %[1]s $argv
+end
+__not_env_hook
+`
+
+// Hook returns the script that installs the directory hook in shell s.
+// The hook calls executable on each directory change to load and unload
+// project variables. It keeps its state in the unexported HookStateVar and
+// wraps not-env in a function that passes the state to it, so 'env clear'
+// sees what the hook loaded while other programs do not inherit it.
+func Hook(s Shell, executable string) string {
+	var script string
+	switch s {
+	case Bash:
+		script = bashHook
+	case Zsh:
+		script = zshHook
+	case Fish:
+		script = fishHook
+	}
+
+	quoted := singleQuote(executable)
+	if s == Fish {
+		quoted = fishQuote(executable)
+	}
+	return fmt.Sprintf(script, quoted)
+}
+
+// Script joins commands into a script suitable for eval or fish's source
+func Script(commands []string) string {
+	if len(commands) == 0 {
+		return ""
+	}
+	return strings.Join(commands, "\n") + "\n"
+}
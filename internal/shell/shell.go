@@ -0,0 +1,72 @@
+// Package shell generates shell commands for loading and unloading
+// variables in bash, zsh and fish.
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shell identifies a supported shell dialect
+type Shell string
+
+const (
+	Bash Shell = "bash"
+	Zsh  Shell = "zsh"
+	Fish Shell = "fish"
+)
+
+// Parse returns the shell with the given name
+func Parse(name string) (Shell, error) {
+	switch Shell(name) {
+	case Bash, Zsh, Fish:
+		return Shell(name), nil
+	}
+	return "", fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish)", name)
+}
+
+// Export returns the command that sets and exports key to value
+func (s Shell) Export(key, value string) string {
+	if s == Fish {
+		return fmt.Sprintf("set -gx %s %s", key, fishQuote(value))
+	}
+	return fmt.Sprintf("export %s=\"%s\"", key, Escape(value))
+}
+
+// SetLocal returns the command that sets the shell variable key to value
+// without exporting it
+func (s Shell) SetLocal(key, value string) string {
+	if s == Fish {
+		return fmt.Sprintf("set -g %s %s", key, fishQuote(value))
+	}
+	return fmt.Sprintf("%s=\"%s\"", key, Escape(value))
+}
+
+// Unset returns the command that removes key from the environment
+func (s Shell) Unset(key string) string {
+	if s == Fish {
+		return fmt.Sprintf("set -e %s", key)
+	}
+	return fmt.Sprintf("unset %s", key)
+}
+
+// Escape escapes value for use inside a POSIX double-quoted string
+func Escape(value string) string {
+	escaped := strings.ReplaceAll(value, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	escaped = strings.ReplaceAll(escaped, `$`, `\$`)
+	escaped = strings.ReplaceAll(escaped, "`", "\\`")
+	return escaped
+}
+
+// singleQuote quotes value for POSIX shells using single quotes
+func singleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes value for fish using single quotes
+func fishQuote(value string) string {
+	escaped := strings.ReplaceAll(value, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, "'", `\'`)
+	return "'" + escaped + "'"
+}
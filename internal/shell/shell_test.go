@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		shell Shell
+		value string
+		want  string
+	}{
+		{shell: Bash, value: "plain", want: `export KEY="plain"`},
+		{shell: Zsh, value: `a"b$c`, want: `export KEY="a\"b\$c"`},
+		{shell: Fish, value: "plain", want: `set -gx KEY 'plain'`},
+		{shell: Fish, value: `it's \ ok`, want: `set -gx KEY 'it\'s \\ ok'`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.shell.Export("KEY", tt.value); got != tt.want {
+			t.Errorf("%s.Export(%q) = %q, want %q", tt.shell, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSetLocal(t *testing.T) {
+	if got := Bash.SetLocal("_S", `a"b`); got != `_S="a\"b"` {
+		t.Errorf("Bash.SetLocal() = %q", got)
+	}
+	if got := Fish.SetLocal("_S", "x"); got != `set -g _S 'x'` {
+		t.Errorf("Fish.SetLocal() = %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("fish"); err != nil {
+		t.Errorf("Parse(fish) returned error: %v", err)
+	}
+	if _, err := Parse("powershell"); err == nil {
+		t.Error("Parse(powershell) should fail")
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	environ := map[string]string{"AWS_REGION": "eu-west-1"}
+
+	state := &State{Dir: "/project"}
+	state.Record("AWS_REGION", environ)
+	state.Record("DB_HOST", environ)
+
+	// A later override must not replace the original value
+	environ["AWS_REGION"] = "us-east-1"
+	state.Record("AWS_REGION", environ)
+
+	encoded, err := state.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	decoded, err := DecodeState(encoded)
+	if err != nil {
+		t.Fatalf("DecodeState() error: %v", err)
+	}
+
+	if decoded.Dir != "/project" {
+		t.Errorf("Dir = %q, want %q", decoded.Dir, "/project")
+	}
+
+	got := strings.Join(Bash.Restore(decoded), "\n")
+	want := strings.Join([]string{
+		`export AWS_REGION="eu-west-1"`,
+		`unset DB_HOST`,
+		`unset _NOT_ENV_STATE`,
+	}, "\n")
+	if got != want {
+		t.Errorf("Restore() = %q, want %q", got, want)
+	}
+
+	// State recorded by 'env set' lives in the exported variable
+	decoded.Dir = ""
+	if got := Bash.Restore(decoded); got[len(got)-1] != "unset NOT_ENV_STATE" {
+		t.Errorf("Restore() of env set state = %q", got)
+	}
+
+	baseline := decoded.Baseline(map[string]string{"AWS_REGION": "us-east-1", "DB_HOST": "db", "PATH": "/bin"})
+	if baseline["AWS_REGION"] != "eu-west-1" || baseline["PATH"] != "/bin" {
+		t.Errorf("Baseline() = %v", baseline)
+	}
+	if _, ok := baseline["DB_HOST"]; ok {
+		t.Errorf("Baseline() should not contain DB_HOST: %v", baseline)
+	}
+}
+
+func TestDecodeStateEmpty(t *testing.T) {
+	state, err := DecodeState("")
+	if err != nil || state != nil {
+		t.Errorf("DecodeState(\"\") = %v, %v; want nil, nil", state, err)
+	}
+
+	if _, err := DecodeState("not base64!"); err == nil {
+		t.Error("DecodeState() should fail on invalid input")
+	}
+}
+
+func TestLoadStateFile(t *testing.T) {
+	state := &State{}
+	state.Record("DB_HOST", map[string]string{"DB_HOST": "mine"})
+
+	path, err := state.WriteFile()
+	if err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	t.Setenv(StateVar, "")
+	t.Setenv(StateFileVar, path)
+
+	loaded, err := LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error: %v", err)
+	}
+	if loaded == nil || loaded.Saved["DB_HOST"] == nil || *loaded.Saved["DB_HOST"] != "mine" {
+		t.Errorf("LoadState() = %+v, want DB_HOST=mine", loaded)
+	}
+}
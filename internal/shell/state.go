@@ -0,0 +1,186 @@
+package shell
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// StateVar is the environment variable recording which variables the CLI
+// loaded into the shell and the values they shadowed
+const StateVar = "NOT_ENV_STATE"
+
+// HookStateVar is the shell variable recording what the directory hook
+// loaded. It is not exported, so other programs started from the shell do
+// not inherit it; the hook passes it to not-env through the environment of
+// each not-env command only.
+const HookStateVar = "_NOT_ENV_STATE"
+
+// StateFileVar is the environment variable pointing to a file holding the
+// state, used instead of StateVar with 'env set --save-restore --state-file'
+const StateFileVar = "NOT_ENV_STATE_FILE"
+
+// State records the variables loaded into a shell. Saved maps each loaded
+// key to the value it had before loading, or nil if it was unset.
+type State struct {
+	// Dir is the project directory the hook loaded the variables for.
+	// It is empty when the variables were loaded by 'env set'.
+	Dir string `json:"dir,omitempty"`
+
+	// Stamp identifies the version of the project file that was loaded
+	Stamp string `json:"stamp,omitempty"`
+
+	Saved map[string]*string `json:"saved"`
+}
+
+// DecodeState decodes a state previously produced by Encode.
+// An empty string decodes to a nil state.
+func DecodeState(encoded string) (*State, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", StateVar, err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", StateVar, err)
+	}
+	if state.Saved == nil {
+		state.Saved = make(map[string]*string)
+	}
+
+	return &state, nil
+}
+
+// LoadState loads the state of the current shell from StateVar or the file
+// named by StateFileVar. It returns nil when no state is recorded.
+func LoadState() (*State, error) {
+	if encoded := os.Getenv(StateVar); encoded != "" {
+		return DecodeState(encoded)
+	}
+
+	path := os.Getenv(StateFileVar)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return DecodeState(strings.TrimSpace(string(data)))
+}
+
+// LoadHookState loads the state recorded by the directory hook, as passed
+// in HookStateVar. It returns nil when the hook has loaded nothing.
+func LoadHookState() (*State, error) {
+	return DecodeState(os.Getenv(HookStateVar))
+}
+
+// WriteFile writes the state to a new private (0600) temporary file and
+// returns its path
+func (st *State) WriteFile() (string, error) {
+	encoded, err := st.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp("", "not-env-state-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(encoded); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// Encode encodes the state into a value safe to store in StateVar
+func (st *State) Encode() (string, error) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Keys returns the loaded keys in sorted order
+func (st *State) Keys() []string {
+	keys := make([]string, 0, len(st.Saved))
+	for key := range st.Saved {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Record saves the value key has in environ before it is overridden.
+// Keys that are already recorded keep their original value.
+func (st *State) Record(key string, environ map[string]string) {
+	if st.Saved == nil {
+		st.Saved = make(map[string]*string)
+	}
+	if _, ok := st.Saved[key]; ok {
+		return
+	}
+	if value, ok := environ[key]; ok {
+		st.Saved[key] = &value
+		return
+	}
+	st.Saved[key] = nil
+}
+
+// RestoreCommand returns the command that puts back the saved value of key,
+// or unsets it if it was originally absent
+func (s Shell) RestoreCommand(st *State, key string) string {
+	if prev := st.Saved[key]; prev != nil {
+		return s.Export(key, *prev)
+	}
+	return s.Unset(key)
+}
+
+// Restore returns the commands that undo everything recorded in st,
+// including removing the state itself
+func (s Shell) Restore(st *State) []string {
+	var commands []string
+	for _, key := range st.Keys() {
+		commands = append(commands, s.RestoreCommand(st, key))
+	}
+	if st.Dir != "" {
+		return append(commands, s.Unset(HookStateVar))
+	}
+	if path := os.Getenv(StateFileVar); path != "" {
+		commands = append(commands, "rm -f "+singleQuote(path), s.Unset(StateFileVar))
+	}
+	return append(commands, s.Unset(StateVar))
+}
+
+// Baseline returns environ as it was before the state's variables were
+// loaded
+func (st *State) Baseline(environ map[string]string) map[string]string {
+	baseline := make(map[string]string, len(environ))
+	for key, value := range environ {
+		baseline[key] = value
+	}
+	if st == nil {
+		return baseline
+	}
+	for key, prev := range st.Saved {
+		if prev == nil {
+			delete(baseline, key)
+		} else {
+			baseline[key] = *prev
+		}
+	}
+	return baseline
+}
@@ -6,19 +6,31 @@
 //
 // Command structure:
 //   - Authentication: login, logout, use
-//   - Environment management: env create/list/delete/import/show/update/keys/set/clear
-//   - Variable management: var list/get/set/delete
+//   - Environment management: env create/list/delete/import/show/update/keys/set/clear/export/diff/clone/promote/rollback/check/validate
+//   - Variable management: var list/get/set/delete/edit/rename/copy/move
+//   - Shell integration: hook, run
+//   - Layered environments: explain
+//   - Templates: render
+//   - Sharing: share, share keygen
+//   - Disaster recovery: backup, restore
 //
 // Configuration is stored in ~/.not-env/config (created via login command).
+// Named profiles in the config hold credentials for several environments and
+// are selected with --profile or NOT_ENV_PROFILE.
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"not-env-cli/internal/commands"
+	"not-env-cli/internal/config"
+	"not-env-cli/internal/filter"
+	"not-env-cli/internal/formats"
 )
 
 var version = "0.1.0"
@@ -28,6 +40,13 @@ var rootCmd = &cobra.Command{
 	Short:   "not-env CLI - Manage environment variables",
 	Long:    "not-env is a CLI tool for managing environment variables stored in not-env-backend",
 	Version: version,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		profile, _ := cmd.Flags().GetString("profile")
+		if profile == "" {
+			profile = os.Getenv("NOT_ENV_PROFILE")
+		}
+		config.SetProfile(profile)
+	},
 }
 
 var loginCmd = &cobra.Command{
@@ -99,19 +118,83 @@ var envDeleteCmd = &cobra.Command{
 
 var envImportCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Import variables from a .env file (ENV_ADMIN)",
+	Short: "Import variables from a .env or other config file (ENV_ADMIN)",
+	Long: `Import variables from a file. Besides .env files, JSON, YAML, TOML, Java
+.properties, docker-compose environment blocks and Kubernetes Secret/ConfigMap
+manifests are supported. The format is detected from the file name (and the
+content of YAML files) unless given with --format. Nested keys of structured
+formats are joined with --separator.
+
+The file is compared with the current variables and the plan (added, changed,
+unchanged and removed keys, values masked as hashes or, below 16 characters,
+lengths) is printed before exactly that plan is applied. Use --plan to only
+print it.
+
+--from-share imports a bundle created with 'not-env share', decrypting it with
+your private key (~/.not-env/identity, see 'not-env share keygen').
+
+--from-env imports the CLI's own environment, e.g. of a running service,
+skipping shell and session variables such as PATH, HOME, PWD and SHLVL, and
+names that are not valid keys, such as bash's exported functions. Extend the
+denylist with --deny or env_deny in the [import] table of .not-env.toml.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 		description, _ := cmd.Flags().GetString("description")
 		filePath, _ := cmd.Flags().GetString("file")
 		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		planOnly, _ := cmd.Flags().GetBool("plan")
+		prune, _ := cmd.Flags().GetBool("prune")
+		noOverwrite, _ := cmd.Flags().GetBool("no-overwrite")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		atomic, _ := cmd.Flags().GetBool("atomic")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
+		expandRefs, _ := cmd.Flags().GetBool("expand")
+		format, _ := cmd.Flags().GetString("format")
+		separator, _ := cmd.Flags().GetString("separator")
+		service, _ := cmd.Flags().GetString("service")
+
+		share, _ := cmd.Flags().GetString("from-share")
+		identity, _ := cmd.Flags().GetString("identity")
+		fromEnv, _ := cmd.Flags().GetBool("from-env")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		deny, _ := cmd.Flags().GetStringArray("deny")
+		only, _ := cmd.Flags().GetStringArray("only")
 
 		// Name validation is handled in EnvImport based on key type
-		if filePath == "" {
-			return fmt.Errorf("--file is required")
+		sources := 0
+		for _, set := range []bool{filePath != "", share != "", fromEnv} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("exactly one of --file, --from-share or --from-env is required")
+		}
+		if !fromEnv && (prefix != "" || len(deny) > 0) {
+			return fmt.Errorf("--prefix and --deny require --from-env")
 		}
 
-		return commands.EnvImport(name, description, filePath, overwrite)
+		return commands.EnvImport(name, description, commands.ImportSource{
+			File:      filePath,
+			Format:    format,
+			Separator: separator,
+			Service:   service,
+			Expand:    expandRefs,
+			Share:     share,
+			Identity:  identity,
+			FromEnv:   fromEnv,
+			EnvPrefix: prefix,
+			EnvDeny:   deny,
+			Only:      only,
+		}, commands.ImportOptions{
+			Overwrite:   overwrite,
+			Plan:        planOnly,
+			Prune:       prune,
+			NoOverwrite: noOverwrite,
+			Interactive: interactive,
+			Atomic:      atomic,
+			NoValidate:  noValidate,
+		})
 	},
 }
 
@@ -158,15 +241,397 @@ var envSetCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Print export commands for all variables (ENV_ADMIN, ENV_READ_ONLY)",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return commands.EnvSet()
+		saveRestore, _ := cmd.Flags().GetBool("save-restore")
+		stateFile, _ := cmd.Flags().GetBool("state-file")
+
+		if stateFile && !saveRestore {
+			return fmt.Errorf("--state-file requires --save-restore")
+		}
+
+		loadOpts, err := loadOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		return commands.EnvSet(commands.EnvSetOptions{
+			LoadOptions: loadOpts,
+			SaveRestore: saveRestore,
+			StateFile:   stateFile,
+		})
 	},
 }
 
 var envClearCmd = &cobra.Command{
 	Use:   "clear",
 	Short: "Print unset commands for all variables (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Print unset commands for all variables.
+
+If the variables were loaded with 'env set --save-restore' or by the directory
+hook, only those variables are removed and the values they replaced are
+restored. Use --restore to fail instead of unsetting every remote key when no
+saved state is found.
+
+Without saved state, the load flags and the project's [load] table select and
+rename keys as in 'env set', so pass the flags used to load the variables.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		restore, _ := cmd.Flags().GetBool("restore")
+		loadOpts, err := loadOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		return commands.EnvClear(restore, loadOpts)
+	},
+}
+
+var hookCmd = &cobra.Command{
+	Use:   "hook <bash|zsh|fish>",
+	Short: "Print a shell hook that loads project variables on directory change",
+	Long: `Print a shell hook that loads the variables of the project in the current
+directory. On each directory change the hook looks for a .not-env.toml file in
+the directory or its parents, loads the environment of the profile it names and
+unloads it again when leaving the project, restoring any values it shadowed.
+
+Fetched variables are cached for a minute in ~/.not-env/cache. Writes made
+with this CLI clear the cache; changes made elsewhere are picked up once it
+expires.
+
+Add one of the following to your shell configuration:
+  bash (~/.bashrc):                 eval "$(not-env hook bash)"
+  zsh (~/.zshrc):                   eval "$(not-env hook zsh)"
+  fish (~/.config/fish/config.fish): not-env hook fish | source`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.Hook(args[0])
+	},
+}
+
+var hookExportCmd = &cobra.Command{
+	Use:    "export <bash|zsh|fish>",
+	Short:  "Print commands that load the current project's variables (used by the hook)",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.HookExport(args[0])
+	},
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- COMMAND [ARGS...]",
+	Short: "Run a command with the environment's variables (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Run a command with the environment's variables added to its environment.
+The command's exit status is passed on, and interrupt and termination signals
+are forwarded to it.
+
+Values of --as-file keys (certificates, credential JSON) are written to files
+in a private temporary directory and the variables are set to the files'
+paths, or with --file-vars KEY_FILE is set alongside KEY. The files are
+overwritten and removed when the command exits.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return commands.EnvClear()
+		asFile, _ := cmd.Flags().GetStringArray("as-file")
+		fileVars, _ := cmd.Flags().GetBool("file-vars")
+
+		loadOpts, err := loadOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		// The child's own output explains its failures; other errors are
+		// reported by main
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return commands.Run(args, commands.RunOptions{
+			Load:     loadOpts,
+			AsFile:   asFile,
+			FileVars: fileVars,
+		})
+	},
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [KEY]",
+	Short: "Show which layer each effective value comes from (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Show which layer each effective value comes from and which layers it shadows.
+Layers are given with --layer and --layer-file in the same order as for
+'env set' and 'run'. Without KEY, every variable is explained.
+
+On a terminal, values are masked unless --reveal is given or the profile sets
+reveal = true.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := ""
+		if len(args) == 1 {
+			key = args[0]
+		}
+		reveal, _ := cmd.Flags().GetBool("reveal")
+		return commands.Explain(key, layersFromFlags(cmd), reveal)
+	},
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render TEMPLATE",
+	Short: "Render a config file template with the variables (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Render a Go text/template file (- for stdin) with the environment's variables
+as data, e.g. {{ .DB_HOST }}. Besides the variables, templates can use:
+
+  required "MSG" VALUE     VALUE, failing with MSG if it is empty
+  default "DEFAULT" VALUE  VALUE, or DEFAULT if it is empty
+  b64dec                   base64-decode a value: {{ .TLS_CERT | b64dec }}
+  json                     encode a value as a JSON string
+  quote                    quote a value with escapes
+
+required and default take the value last, so they work in pipelines:
+{{ .DB_PORT | default "5432" }}. Referencing a missing key fails unless
+--allow-missing is given; use {{ index . "KEY" | default "VALUE" }} for a key
+that may be missing. Output goes
+to stdout, or with --output to a file that is created with permissions 0600
+and replaced atomically.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		allowMissing, _ := cmd.Flags().GetBool("allow-missing")
+
+		loadOpts, err := loadOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		return commands.Render(args[0], commands.RenderOptions{
+			Output:       output,
+			AllowMissing: allowMissing,
+			Load:         loadOpts,
+		})
+	},
+}
+
+var envExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write all variables in a file format (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Write all variables in one of several formats, sorted by key so the output is
+diff-friendly. Output goes to stdout, or with --output to a file that is
+created with permissions 0600 and replaced atomically.
+
+Formats: ` + strings.Join(formats.ExportFormats, ", "),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		name, _ := cmd.Flags().GetString("name")
+
+		loadOpts, err := loadOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		return commands.EnvExport(format, output, name, loadOpts)
+	},
+}
+
+var envDiffCmd = &cobra.Command{
+	Use:   "diff A B",
+	Short: "Compare two environments, profiles or local files",
+	Long: `Compare the variables of two sides and print the keys only in A, only in B
+and those whose values differ. Each side is a profile, a stored environment
+(found by name among the saved keys) or a local dotenv/JSON/other config file;
+prefix it with profile:, env: or file: to disambiguate.
+
+Values are masked as short hashes (lengths below 16 characters) unless
+--show-values is given. Exits with status 1 if there are differences and 2 on
+errors.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showValues, _ := cmd.Flags().GetBool("show-values")
+
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return statusError(commands.EnvDiff(args[0], args[1], showValues))
+	},
+}
+
+var envCloneCmd = &cobra.Command{
+	Use:   "clone --from SOURCE --name NAME",
+	Short: "Create an environment with the variables of another (APP_ADMIN)",
+	Long: `Create an environment and copy the variables of SOURCE into it. SOURCE is a
+profile, a stored environment (found by name among the saved keys) or a local
+file, as for 'env diff'. The environment is created with the current APP_ADMIN
+key and written with its new ENV_ADMIN key, which is saved as a profile named
+after the environment (see --save-profile and --no-save).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		name, _ := cmd.Flags().GetString("name")
+		description, _ := cmd.Flags().GetString("description")
+		only, _ := cmd.Flags().GetStringArray("only")
+		set, _ := cmd.Flags().GetStringArray("set")
+		saveProfile, _ := cmd.Flags().GetString("save-profile")
+		noSave, _ := cmd.Flags().GetBool("no-save")
+
+		if from == "" {
+			return fmt.Errorf("--from is required")
+		}
+
+		return commands.EnvClone(from, name, commands.CloneOptions{
+			Description: description,
+			Only:        only,
+			Set:         set,
+			Profile:     saveProfile,
+			NoSave:      noSave,
+		})
+	},
+}
+
+var envRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Undo the last atomic import (ENV_ADMIN)",
+	Long: `Restore the variables changed by the last 'env import --atomic' into the
+current environment to the values they had before, removing keys it added.
+The snapshot is kept in ~/.not-env/snapshots until the rollback succeeds.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		return commands.EnvRollback(yes)
+	},
+}
+
+var envCheckCmd = &cobra.Command{
+	Use:   "check [--against FILE]",
+	Short: "Check that required keys are set (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Compare the current environment with the keys of a file such as .env.example
+(any import format), or with [check] required in .not-env.toml, and report
+missing keys, empty values and unexpected extra keys. Exits with status 1 if
+the check fails and 2 on errors, so CI can gate deploys.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		against, _ := cmd.Flags().GetString("against")
+		allowExtra, _ := cmd.Flags().GetBool("allow-extra")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return statusError(commands.EnvCheck(commands.CheckOptions{
+			Against:    against,
+			AllowExtra: allowExtra,
+			JSON:       asJSON,
+		}))
+	},
+}
+
+var envValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the current environment against not-env.schema.toml (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Check the current environment against the schema file not-env.schema.toml,
+found in the working directory or its parents. Required keys without a default
+must be set and every declared key must have a value of its type (int, bool,
+url, email, duration, enum, regex, json). Exits with status 1 if the check
+fails and 2 on errors.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return statusError(commands.EnvValidate())
+	},
+}
+
+var envPromoteCmd = &cobra.Command{
+	Use:   "promote FROM TO",
+	Short: "Copy selected variables from one environment to another",
+	Long: `Copy variables from FROM to TO, e.g. from staging to production. FROM and TO
+are profiles or stored environments (FROM may also be a local file), possibly
+on different backends; TO needs an ENV_ADMIN key.
+
+The changes are shown (values masked) and must be confirmed. Promoting into a
+protected profile (protected = true in ~/.not-env/config) requires typing its
+name, or --confirm NAME in scripts. A report of the applied changes is written
+to ~/.not-env/promotions unless --report is given.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, _ := cmd.Flags().GetStringArray("keys")
+		prune, _ := cmd.Flags().GetBool("prune")
+		yes, _ := cmd.Flags().GetBool("yes")
+		confirmName, _ := cmd.Flags().GetString("confirm")
+		report, _ := cmd.Flags().GetString("report")
+
+		return commands.EnvPromote(args[0], args[1], commands.PromoteOptions{
+			Keys:    keys,
+			Prune:   prune,
+			Yes:     yes,
+			Confirm: confirmName,
+			Report:  report,
+		})
+	},
+}
+
+var shareCmd = &cobra.Command{
+	Use:   "share --to PUBLIC_KEY",
+	Short: "Encrypt variables for a teammate (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Write selected variables of the current environment as an encrypted bundle
+that only the holders of the given age public keys can read. The recipient
+creates a key pair with 'not-env share keygen' and imports the bundle into
+their own environment with 'not-env env import --from-share FILE'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetStringArray("to")
+		keys, _ := cmd.Flags().GetStringArray("keys")
+		output, _ := cmd.Flags().GetString("output")
+		return commands.Share(to, keys, output)
+	},
+}
+
+var shareKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create the private key for receiving shared variables",
+	Long: `Create an age key pair in ~/.not-env/identity (permissions 0600) and print
+the public key to give to teammates. An existing key is reused unless --force
+is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		return commands.ShareKeygen(force)
+	},
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup --out FILE",
+	Short: "Write an encrypted backup of environments (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `Write the metadata and variables of every environment with a saved key
+(default credentials and all profiles), or only the current one with
+--current, to an age-encrypted archive. The archive is encrypted to the
+public keys given with --recipient, or else with a passphrase (prompted, or
+from NOT_ENV_PASSPHRASE).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		current, _ := cmd.Flags().GetBool("current")
+		recipients, _ := cmd.Flags().GetStringArray("recipient")
+
+		if out == "" {
+			return fmt.Errorf("--out is required")
+		}
+
+		return commands.Backup(out, commands.BackupOptions{
+			Current:    current,
+			Recipients: recipients,
+		})
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore FILE",
+	Short: "Restore environments from an encrypted backup (APP_ADMIN, ENV_ADMIN)",
+	Long: `Decrypt a backup and verify its integrity, then recreate its environments.
+With an APP_ADMIN key the environments are created (--into restores a single
+one under another name) and their new ENV_ADMIN keys saved as profiles. With
+an ENV_ADMIN key, one environment of the backup is restored into the key's own
+environment after showing the plan.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, _ := cmd.Flags().GetString("env")
+		into, _ := cmd.Flags().GetString("into")
+		identity, _ := cmd.Flags().GetString("identity")
+		prune, _ := cmd.Flags().GetBool("prune")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		return commands.Restore(args[0], commands.RestoreOptions{
+			Env:      env,
+			Into:     into,
+			Identity: identity,
+			Prune:    prune,
+			Yes:      yes,
+		})
 	},
 }
 
@@ -178,29 +643,185 @@ var varCmd = &cobra.Command{
 var varListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all variables (ENV_ADMIN, ENV_READ_ONLY)",
+	Long: `List variables as KEY=VALUE lines by default, or as a table (key and value
+by default), JSON, YAML or CSV (all columns by default). --columns selects and
+orders the columns and implies --format table: ` + strings.Join(commands.ListColumns, ",") + `.
+Table timestamps are relative ("3h ago") unless --local-time is given; the
+other formats use RFC 3339 in UTC (or local time). JSON and YAML keys are
+sorted, so the output is stable for tools like jq.
+
+On a terminal, values are masked as their length (and a short hash for values
+of 16 characters or more) unless --reveal is given or the profile sets
+reveal = true; piped output always has the real values.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return commands.VarList()
+		format, _ := cmd.Flags().GetString("format")
+		columns, _ := cmd.Flags().GetStringSlice("columns")
+		sortBy, _ := cmd.Flags().GetString("sort")
+		glob, _ := cmd.Flags().GetString("filter")
+		regex, _ := cmd.Flags().GetString("regex")
+		localTime, _ := cmd.Flags().GetBool("local-time")
+		reveal, _ := cmd.Flags().GetBool("reveal")
+
+		return commands.VarList(commands.VarListOptions{
+			Format:    format,
+			Columns:   columns,
+			Sort:      sortBy,
+			Filter:    glob,
+			Regex:     regex,
+			LocalTime: localTime,
+			Reveal:    reveal,
+		})
 	},
 }
 
 var varGetCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Get a variable value (ENV_ADMIN, ENV_READ_ONLY)",
-	Args:  cobra.ExactArgs(1),
+	Long: `Get a variable. On a terminal, the value is masked as its length (and a
+short hash from 16 characters) unless --reveal is given or the profile sets
+reveal = true; piped output always has the real value.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return commands.VarGet(args[0])
+		resolved, _ := cmd.Flags().GetBool("resolved")
+		expandEnv, _ := cmd.Flags().GetBool("expand-env")
+		reveal, _ := cmd.Flags().GetBool("reveal")
+		return commands.VarGet(args[0], resolved || expandEnv, expandEnv, reveal)
 	},
 }
 
 var varSetCmd = &cobra.Command{
-	Use:   "set",
+	Use:   "set KEY [VALUE]",
 	Short: "Set a variable value (ENV_ADMIN)",
-	Args:  cobra.ExactArgs(2),
+	Long: `Set a variable. Instead of VALUE, which ends up in shell history and ps
+output, the value can be read with --stdin, --from-file (read byte for byte),
+--prompt (hidden terminal input, asked twice) or --from-cmd (the output of a
+shell command). One trailing newline is removed from stdin and command output.
+Inputs are limited to 1 MiB; binary data must be stored with --base64.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
+		stdin, _ := cmd.Flags().GetBool("stdin")
+		fromFile, _ := cmd.Flags().GetString("from-file")
+		prompt, _ := cmd.Flags().GetBool("prompt")
+		fromCmd, _ := cmd.Flags().GetString("from-cmd")
+		b64, _ := cmd.Flags().GetBool("base64")
+
+		opts := commands.VarSetOptions{
+			NoValidate: noValidate,
+			Stdin:      stdin,
+			FromFile:   fromFile,
+			Prompt:     prompt,
+			FromCmd:    fromCmd,
+			Base64:     b64,
+		}
+
+		value := ""
+		if len(args) == 2 {
+			value = args[1]
+		}
+		if (len(args) == 2) == (stdin || fromFile != "" || prompt || fromCmd != "") {
+			return fmt.Errorf("give either VALUE or one of --stdin, --from-file, --prompt, --from-cmd")
+		}
+
+		return commands.VarSet(args[0], value, opts)
+	},
+}
+
+var varEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit all variables in $EDITOR (ENV_ADMIN)",
+	Long: `Open the variables as a dotenv file in $VISUAL or $EDITOR (default vi). After
+the editor exits, the added, changed and deleted keys are shown (values
+masked) and applied after confirmation. Syntax errors and values that violate
+not-env.schema.toml reopen the editor.
+
+The file is created with permissions 0600 and overwritten and removed
+afterwards, also when the command is interrupted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.VarEdit()
+	},
+}
+
+var varRenameCmd = &cobra.Command{
+	Use:   "rename OLD NEW",
+	Short: "Rename variables (ENV_ADMIN)",
+	Long: `Rename a variable, or all variables matching a pattern with one *, whose
+match replaces the * of NEW:
+
+  not-env var rename 'LEGACY_*' 'APP_*'
+
+Each new key is written and read back before the old one is deleted. Keys that
+already exist with a different value are not overwritten without --force.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		return commands.VarTransfer(args[0], args[1], commands.TransferOptions{
+			Delete: true,
+			Force:  force,
+		})
+	},
+}
+
+var varCopyCmd = &cobra.Command{
+	Use:   "copy KEY [NEW] [--to-env ENV]",
+	Short: "Copy variables within or to another environment (ENV_ADMIN)",
+	Long: `Copy a variable, or all variables matching a pattern with one *, under a new
+name and/or into another environment (a profile or stored environment name,
+see 'env diff'):
+
+  not-env var copy DB_URL --to-env staging
+  not-env var copy 'DB_*' 'REPLICA_DB_*'
+
+Keys that already exist with a different value are not overwritten without
+--force. Protected targets require typing their name, or --confirm NAME.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return transferCmd(cmd, args, false)
+	},
+}
+
+var varMoveCmd = &cobra.Command{
+	Use:   "move KEY [NEW] --to-env ENV",
+	Short: "Move variables to another environment (ENV_ADMIN)",
+	Long: `Move a variable, or all variables matching a pattern with one *, into another
+environment, optionally under a new name. Each copy is written and read back
+before the original is deleted. Keys that already exist with a different value
+are not overwritten without --force. Protected targets require typing their
+name, or --confirm NAME. Use 'var rename' within one environment.`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return commands.VarSet(args[0], args[1])
+		toEnv, _ := cmd.Flags().GetString("to-env")
+		if toEnv == "" {
+			return fmt.Errorf("--to-env is required (use 'var rename' within one environment)")
+		}
+		return transferCmd(cmd, args, true)
 	},
 }
 
+// transferCmd runs 'var copy' and 'var move': KEY keeps its name unless
+// NEW is given
+func transferCmd(cmd *cobra.Command, args []string, del bool) error {
+	toEnv, _ := cmd.Flags().GetString("to-env")
+	force, _ := cmd.Flags().GetBool("force")
+	confirmName, _ := cmd.Flags().GetString("confirm")
+
+	to := args[0]
+	if len(args) == 2 {
+		to = args[1]
+	} else if toEnv == "" {
+		return fmt.Errorf("give NEW or --to-env")
+	}
+
+	return commands.VarTransfer(args[0], to, commands.TransferOptions{
+		ToEnv:   toEnv,
+		Delete:  del,
+		Force:   force,
+		Confirm: confirmName,
+	})
+}
+
 var varDeleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete a variable (ENV_ADMIN)",
@@ -210,7 +831,106 @@ var varDeleteCmd = &cobra.Command{
 	},
 }
 
+// statusError prints an error and turns it into exit status 2, for
+// commands that report their result with exit status 1 (diff, check).
+// Result statuses are passed on unchanged.
+func statusError(err error) error {
+	var exitErr *commands.ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return &commands.ExitError{Code: 2}
+}
+
+// layerFlag appends to a list of layers shared by --layer and --layer-file,
+// so that the layers keep the order they were given in
+type layerFlag struct {
+	layers *[]commands.Layer
+	file   bool
+}
+
+func (f *layerFlag) String() string {
+	return ""
+}
+
+func (f *layerFlag) Set(value string) error {
+	if value == "" {
+		return fmt.Errorf("layer cannot be empty")
+	}
+	if f.file {
+		*f.layers = append(*f.layers, commands.Layer{File: value})
+	} else {
+		*f.layers = append(*f.layers, commands.Layer{Profile: value})
+	}
+	return nil
+}
+
+func (f *layerFlag) Type() string {
+	if f.file {
+		return "path"
+	}
+	return "profile"
+}
+
+// addLayerFlags adds the --layer and --layer-file flags
+func addLayerFlags(cmd *cobra.Command) {
+	layers := &[]commands.Layer{}
+	cmd.Flags().Var(&layerFlag{layers: layers}, "layer", "Merge the environment of a profile (repeatable, later layers win)")
+	cmd.Flags().Var(&layerFlag{layers: layers, file: true}, "layer-file", "Merge variables from a .env file (repeatable, later layers win)")
+}
+
+// layersFromFlags returns the layers given with the flags added by
+// addLayerFlags, in order
+func layersFromFlags(cmd *cobra.Command) []commands.Layer {
+	return *cmd.Flags().Lookup("layer").Value.(*layerFlag).layers
+}
+
+// addLoadFlags adds the flags that select, merge and rename loaded variables
+func addLoadFlags(cmd *cobra.Command) {
+	addLayerFlags(cmd)
+	cmd.Flags().StringArray("only", nil, "Load only keys matching GLOB (repeatable)")
+	cmd.Flags().StringArray("exclude", nil, "Skip keys matching GLOB (repeatable)")
+	cmd.Flags().String("strip-prefix", "", "Remove PREFIX from loaded keys")
+	cmd.Flags().String("add-prefix", "", "Prepend PREFIX to loaded keys")
+	cmd.Flags().StringArray("rename", nil, "Load key OLD as NEW, as OLD=NEW (repeatable)")
+	cmd.Flags().Bool("expand", false, "Resolve ${VAR} and ${VAR:-default} references between variables")
+	cmd.Flags().Bool("expand-env", false, "Like --expand, also resolving references from the process environment")
+}
+
+// loadOptionsFromFlags reads the flags added by addLoadFlags
+func loadOptionsFromFlags(cmd *cobra.Command) (commands.LoadOptions, error) {
+	only, _ := cmd.Flags().GetStringArray("only")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+	stripPrefix, _ := cmd.Flags().GetString("strip-prefix")
+	addPrefix, _ := cmd.Flags().GetString("add-prefix")
+	renames, _ := cmd.Flags().GetStringArray("rename")
+	expandVars, _ := cmd.Flags().GetBool("expand")
+	expandEnv, _ := cmd.Flags().GetBool("expand-env")
+
+	rename, err := filter.ParseRename(renames)
+	if err != nil {
+		return commands.LoadOptions{}, err
+	}
+
+	opts := commands.LoadOptions{
+		Layers: layersFromFlags(cmd),
+		Filter: filter.Options{
+			Only:        only,
+			Exclude:     exclude,
+			StripPrefix: stripPrefix,
+			AddPrefix:   addPrefix,
+			Rename:      rename,
+		},
+		Expand:    expandVars,
+		ExpandEnv: expandEnv,
+	}
+	return opts, opts.Filter.Validate()
+}
+
 func init() {
+	rootCmd.PersistentFlags().String("profile", "", "Credentials profile to use (default from NOT_ENV_PROFILE)")
+
 	// Login/logout/use
 	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
@@ -231,16 +951,101 @@ func init() {
 	envCmd.AddCommand(envKeysCmd)
 	envCmd.AddCommand(envSetCmd)
 	envCmd.AddCommand(envClearCmd)
+	envCmd.AddCommand(envExportCmd)
+	envCmd.AddCommand(envDiffCmd)
+	envCmd.AddCommand(envCloneCmd)
+	envCmd.AddCommand(envPromoteCmd)
+	envCmd.AddCommand(envRollbackCmd)
+	envCmd.AddCommand(envCheckCmd)
+	envCmd.AddCommand(envValidateCmd)
 
 	envCreateCmd.Flags().String("name", "", "Environment name")
 	envCreateCmd.Flags().String("description", "", "Environment description")
 	envDeleteCmd.Flags().Int64("id", 0, "Environment ID")
 	envImportCmd.Flags().String("name", "", "Environment name")
 	envImportCmd.Flags().String("description", "", "Environment description")
-	envImportCmd.Flags().String("file", "", "Path to .env file")
+	envImportCmd.Flags().String("file", "", "Path to .env or config file (- for stdin)")
 	envImportCmd.Flags().Bool("overwrite", false, "Overwrite existing environment")
+	envImportCmd.Flags().Bool("plan", false, "Only print the plan, do not apply it")
+	envImportCmd.Flags().Bool("prune", false, "Delete variables that are not in the file")
+	envImportCmd.Flags().Bool("no-overwrite", false, "Only add missing variables, keep existing values")
+	envImportCmd.Flags().BoolP("interactive", "i", false, "Ask before overwriting each changed variable and before applying")
+	envImportCmd.Flags().Bool("atomic", false, "Roll back all changes if any fails or the import is interrupted")
+	envImportCmd.Flags().Bool("expand", false, "Resolve ${VAR} references between the file's variables")
+	envImportCmd.Flags().String("format", "", "Input format: "+strings.Join(formats.ImportFormats, ", ")+" (default: detect)")
+	envImportCmd.Flags().String("separator", "_", "Separator for flattened nested keys")
+	envImportCmd.Flags().String("from-share", "", "Import an encrypted bundle created with 'not-env share' (- for stdin)")
+	envImportCmd.Flags().String("identity", "", "Private key file for --from-share (default ~/.not-env/identity)")
+	envImportCmd.Flags().Bool("from-env", false, "Import variables from the current process environment")
+	envImportCmd.Flags().String("prefix", "", "With --from-env, only import variables starting with PREFIX")
+	envImportCmd.Flags().StringArray("deny", nil, "With --from-env, also skip variables matching GLOB (repeatable)")
+	envImportCmd.Flags().StringArray("only", nil, "Only import keys matching GLOB (repeatable)")
+	envImportCmd.Flags().String("service", "", "Service whose environment to import from a compose file")
+	envImportCmd.Flags().Bool("no-validate", false, "Do not check values against not-env.schema.toml")
 	envUpdateCmd.Flags().String("name", "", "New environment name")
 	envUpdateCmd.Flags().String("description", "", "New environment description")
+	envSetCmd.Flags().Bool("save-restore", false, "Record overridden values so 'env clear --restore' can restore them")
+	envSetCmd.Flags().Bool("state-file", false, "Keep recorded values in a private temp file instead of NOT_ENV_STATE")
+	addLoadFlags(envSetCmd)
+	envExportCmd.Flags().String("format", formats.Dotenv, "Output format: "+strings.Join(formats.ExportFormats, ", "))
+	envExportCmd.Flags().StringP("output", "o", "", "Write to FILE (0600, atomic) instead of stdout")
+	envExportCmd.Flags().String("name", "", "Name of Kubernetes manifests (default: environment name)")
+	addLoadFlags(envExportCmd)
+	envCloneCmd.Flags().String("from", "", "Profile, environment or file to copy from")
+	envCloneCmd.Flags().String("name", "", "New environment name")
+	envCloneCmd.Flags().String("description", "", "New environment description")
+	envCloneCmd.Flags().StringArray("only", nil, "Only copy keys matching GLOB (repeatable)")
+	envCloneCmd.Flags().StringArray("set", nil, "Set KEY=VALUE in the new environment (repeatable)")
+	envCloneCmd.Flags().String("save-profile", "", "Profile to save the new ENV_ADMIN key under (default: NAME)")
+	envCloneCmd.Flags().Bool("no-save", false, "Do not save the new keys as a profile")
+	envCheckCmd.Flags().String("against", "", "File whose keys are required, e.g. .env.example")
+	envCheckCmd.Flags().Bool("allow-extra", false, "Do not fail on keys that are not required")
+	envCheckCmd.Flags().Bool("json", false, "Print the result as JSON")
+	envRollbackCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	envPromoteCmd.Flags().StringArray("keys", nil, "Only promote keys matching GLOB (repeatable)")
+	envPromoteCmd.Flags().Bool("prune", false, "Delete selected keys of TO that are not in FROM")
+	envPromoteCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation (unprotected targets only)")
+	envPromoteCmd.Flags().String("confirm", "", "Name of a protected target, confirming without a prompt")
+	envPromoteCmd.Flags().String("report", "", "Path of the promotion report")
+	envDiffCmd.Flags().Bool("show-values", false, "Show values instead of hashes")
+	envClearCmd.Flags().Bool("restore", false, "Restore recorded values; fail if no saved state is found")
+	addLoadFlags(envClearCmd)
+
+	// Shell hook and run
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(hookExportCmd)
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().SetInterspersed(false)
+	addLoadFlags(runCmd)
+	runCmd.Flags().StringArray("as-file", nil, "Pass KEY as the path of a private file holding its value (repeatable)")
+	runCmd.Flags().Bool("file-vars", false, "With --as-file, set KEY_FILE to the path and keep KEY")
+	rootCmd.AddCommand(explainCmd)
+	addLayerFlags(explainCmd)
+	explainCmd.Flags().Bool("reveal", false, "Show values on a terminal instead of masking them")
+	rootCmd.AddCommand(renderCmd)
+	addLoadFlags(renderCmd)
+	renderCmd.Flags().StringP("output", "o", "", "Write to FILE (0600) instead of stdout")
+	renderCmd.Flags().Bool("allow-missing", false, "Render missing keys as empty strings")
+
+	// Sharing
+	rootCmd.AddCommand(shareCmd)
+	shareCmd.AddCommand(shareKeygenCmd)
+	shareCmd.Flags().StringArray("to", nil, "Recipient age public key or file of keys (repeatable)")
+	shareCmd.Flags().StringArray("keys", nil, "Only share keys matching GLOB (repeatable)")
+	shareCmd.Flags().StringP("output", "o", "", "Write the bundle to FILE instead of stdout")
+	shareKeygenCmd.Flags().Bool("force", false, "Replace an existing key")
+
+	// Backup and restore
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	backupCmd.Flags().String("out", "", "Path of the encrypted backup")
+	backupCmd.Flags().Bool("current", false, "Only back up the current environment")
+	backupCmd.Flags().StringArray("recipient", nil, "Encrypt to an age public key or a file of keys (repeatable)")
+	restoreCmd.Flags().String("env", "", "Restore only the environment with this name")
+	restoreCmd.Flags().String("into", "", "Restore the environment under a new name")
+	restoreCmd.Flags().String("identity", "", "age private key file for backups encrypted to public keys")
+	restoreCmd.Flags().Bool("prune", false, "Delete variables not in the backup (ENV_ADMIN)")
+	restoreCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation (ENV_ADMIN)")
 
 	// Variable commands
 	rootCmd.AddCommand(varCmd)
@@ -248,10 +1053,41 @@ func init() {
 	varCmd.AddCommand(varGetCmd)
 	varCmd.AddCommand(varSetCmd)
 	varCmd.AddCommand(varDeleteCmd)
+	varCmd.AddCommand(varEditCmd)
+	varCmd.AddCommand(varRenameCmd)
+	varCmd.AddCommand(varCopyCmd)
+	varCmd.AddCommand(varMoveCmd)
+
+	varSetCmd.Flags().Bool("no-validate", false, "Do not check the value against not-env.schema.toml")
+	varSetCmd.Flags().Bool("stdin", false, "Read the value from stdin")
+	varSetCmd.Flags().String("from-file", "", "Read the value from a file")
+	varSetCmd.Flags().Bool("prompt", false, "Prompt for the value without echo")
+	varSetCmd.Flags().String("from-cmd", "", "Read the value from the output of a shell command")
+	varSetCmd.Flags().Bool("base64", false, "Store the value base64-encoded (for binary data)")
+	varRenameCmd.Flags().Bool("force", false, "Overwrite existing keys")
+	for _, c := range []*cobra.Command{varCopyCmd, varMoveCmd} {
+		c.Flags().String("to-env", "", "Target profile or environment name")
+		c.Flags().Bool("force", false, "Overwrite existing keys")
+		c.Flags().String("confirm", "", "Name of a protected target, confirming without a prompt")
+	}
+	varListCmd.Flags().Bool("reveal", false, "Show values on a terminal instead of masking them")
+	varListCmd.Flags().String("format", "", "Output format: "+strings.Join(commands.ListFormats, ", "))
+	varListCmd.Flags().StringSlice("columns", nil, "Columns to show, comma-separated: "+strings.Join(commands.ListColumns, ","))
+	varListCmd.Flags().String("sort", "key", "Sort by key or updated (newest first)")
+	varListCmd.Flags().String("filter", "", "Only list keys matching GLOB")
+	varListCmd.Flags().String("regex", "", "Only list keys matching a regular expression")
+	varListCmd.Flags().Bool("local-time", false, "Show timestamps in local time")
+	varGetCmd.Flags().Bool("reveal", false, "Show the value on a terminal instead of masking it")
+	varGetCmd.Flags().Bool("resolved", false, "Resolve ${VAR} references against the other variables")
+	varGetCmd.Flags().Bool("expand-env", false, "Like --resolved, also resolving references from the process environment")
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}